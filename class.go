@@ -2,26 +2,51 @@ package main
 
 import (
 	"log"
+	"sort"
 )
 
 type Class struct {
-	n              estring
-	f              File
-	l              int
-	super          *Class
-	sub            []*Class // SortedList
-	superclassName estring
-	m              []*Function // SortedList
-	db             *DocBlock
-	done           bool
+	n    estring
+	f    File
+	l    int
+	b    []*Base
+	sub  []*Class    // SortedList
+	m    []*Function // SortedList
+	e    []*Enum
+	v    []*Variable
+	k    estring
+	tp   estring
+	db   *DocBlock
+	done bool
+}
+
+/*! The Base type models one base class of a Class: its name as
+  written in the header, its access specifier, and the Class it
+  refers to, which buildHierarchy() finds.
+*/
+
+type Base struct {
+	n      estring
+	access estring
+	c      *Class
 }
 
 /*! \class Class class.h
 
-  The Class class models a C++ class and its documentation.
+  The Class class models a C++ class and its documentation. Structs
+  and unions are modelled as classes too; kind() tells them apart.
+  A nested class is named after its outer class, e.g.
+  "Outer::Inner".
 
-  A Class has zero or one parent classes, any number of member
-  functions and one documentation block.
+  A Class has any number of base classes, any number of member
+  functions and variables and one documentation block. Its name is
+  fully qualified, e.g. "geo::Shape" for class Shape in namespace
+  geo.
+
+  A class template has template parameters. An explicit
+  specialization is a separate Class whose name includes the
+  template arguments, e.g. "Foo<int>", and which specializes() the
+  primary template.
 
   The file has an origin file and line.
 */
@@ -64,8 +89,102 @@ func (this Class) name() estring {
 	return this.n
 }
 
+/*! Records whether this class was declared as a "class", "struct"
+  or "union". */
+
+func (this *Class) setKind(kind estring) {
+	this.k = kind
+}
+
+/*! Returns "class", "struct" or "union", depending on how the class
+  was declared. Classes whose declaration hasn't been seen are
+  considered to be classes. */
+
+func (this Class) kind() estring {
+	if this.k.isEmpty() {
+		return "class"
+	}
+	return this.k
+}
+
+/*! Records that this class is a template with the parameter list
+  \a tp, e.g. "<typename T>". An explicit specialization has the
+  empty list "<>". */
+
+func (this *Class) setTemplateParameters(tp estring) {
+	this.tp = tp
+}
+
+/*! Returns the template parameter list given to
+  setTemplateParameters(), or an empty string if this class is not a
+  template. */
+
+func (this Class) templateParameters() estring {
+	return this.tp
+}
+
+/*! Returns a pointer to the primary template this class is an
+  explicit specialization of, or a null pointer if it isn't a
+  specialization (or the primary template is unknown). */
+
+func (this Class) specializes() *Class {
+	n := unqualified(this.n)
+	i := n.find("<")
+	if i < 0 {
+		return nil
+	}
+	return findClass(qualified(scopeOf(this.n), n.mid(0, i)))
+}
+
+/*! Returns the documented explicit specializations of this class
+  template, sorted by name. */
+
+func (this *Class) specializations() []*Class {
+	var r []*Class
+	for _, c := range documentedClasses() {
+		if c.specializes() == this {
+			r = append(r, c)
+		}
+	}
+	return r
+}
+
+/*! Returns the Namespace this class is declared in, or a null
+  pointer if it is in the global namespace (or nested in a class). */
+
+func (this Class) namespace() *Namespace {
+	return findNamespace(scopeOf(this.n))
+}
+
+/*! Returns a pointer to the class this class is nested in, or a
+  null pointer if it is declared at namespace level. */
+
+func (this Class) outer() *Class {
+	return findClass(scopeOf(this.n))
+}
+
+/*! Returns the documented classes nested directly in this class,
+  sorted by name. */
+
+func (this *Class) nestedClasses() []*Class {
+	var r []*Class
+	for _, c := range documentedClasses() {
+		if c.outer() == this {
+			r = append(r, c)
+		}
+	}
+	return r
+}
+
+/*! Returns the base name of the page documenting this class. */
+
+func (this Class) pageName() estring {
+	return pageName(this.n)
+}
+
 /*! Returns a pointer to the Class object whose name() is \a s, or a
-  null pointer of there is no such object.
+  null pointer of there is no such object. \a s must be fully
+  qualified; lookupClass() handles names seen in a scope.
 */
 
 func findClass(s estring) *Class {
@@ -77,15 +196,19 @@ func findClass(s estring) *Class {
 	return nil
 }
 
-/*! Notifies this Class that \a cn is its parent class. The initial
-  value is an empty string, corresponding to a class that inherits
-  nothing.
-
-  Note that udoc does not support multiple or non-public inheritance.
+/*! Notifies this Class that it inherits \a cn, with access specifier
+  \a access ("public", "protected" or "private"). Bases are recorded
+  in declaration order; a class which inherits nothing has none.
 */
 
-func (this *Class) setParent(cn estring) {
-	this.superclassName = cn
+func (this *Class) addBase(cn, access estring) {
+	this.b = append(this.b, &Base{n: cn, access: access})
+}
+
+/*! Returns the base classes of this class, in declaration order. */
+
+func (this Class) bases() []*Base {
+	return this.b
 }
 
 /*! Returns the line number where this class was first seen. Should
@@ -117,6 +240,23 @@ func outputClasses() {
 	}
 }
 
+/*! Returns a list of all classes which have a documentation page,
+  ie. which have a DocBlock that isn't \internal, sorted by name.
+*/
+
+func documentedClasses() []*Class {
+	var r []*Class
+	for _, c := range classes {
+		if c.db != nil && c.f != nil && !c.db.isInternal() {
+			r = append(r, c)
+		}
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].name().lower() < r[j].name().lower()
+	})
+	return r
+}
+
 func (this *Class) setDocBlock(d *DocBlock) {
 	this.db = d
 }
@@ -125,8 +265,82 @@ func (this *Class) insert(memb *Function) {
 	this.m = append(this.m, memb)
 }
 
+func (this *Class) insertEnum(e *Enum) {
+	this.e = append(this.e, e)
+}
+
+func (this *Class) insertVariable(v *Variable) {
+	this.v = append(this.v, v)
+}
+
+/*! Returns the enums declared in this class, in declaration order. */
+
+func (this Class) enums() []*Enum {
+	return this.e
+}
+
+/*! Returns a pointer to the enum named \a n in this class, or a null
+  pointer if there is no such enum. */
+
+func (this Class) enumNamed(n estring) *Enum {
+	if n.isEmpty() {
+		return nil
+	}
+	for _, e := range this.e {
+		if e.name() == n {
+			return e
+		}
+	}
+	return nil
+}
+
+/*! Returns the member variables of this class, in declaration
+  order. */
+
+func (this Class) variables() []*Variable {
+	return this.v
+}
+
+/*! Returns the typedefs and aliases declared in this class, in
+  declaration order. */
+
+func (this *Class) typedefs() []*Typedef {
+	var r []*Typedef
+	for _, td := range typedefs {
+		if td.parent() == this {
+			r = append(r, td)
+		}
+	}
+	return r
+}
+
+/*! Returns a pointer to the member variable named \a n in this
+  class, or a null pointer if there is no such variable. */
+
+func (this Class) variableNamed(n estring) *Variable {
+	for _, v := range this.v {
+		if v.name() == n {
+			return v
+		}
+	}
+	return nil
+}
+
+/*! Returns a pointer to the enum in this class which has a value
+  named \a v, or a null pointer if there is no such enum. */
+
+func (this Class) enumWithValue(v estring) *Enum {
+	for _, e := range this.e {
+		if e.hasValue(v) {
+			return e
+		}
+	}
+	return nil
+}
+
 /*! Does everything necessary to generate output for this class and
-  all of its member functions.
+  all of its typedefs, enums, member functions, member variables and
+  related functions.
 */
 
 func (this *Class) generateOutput() {
@@ -149,15 +363,54 @@ func (this *Class) generateOutput() {
 		this.db.generate()
 	}
 
+	for _, td := range this.typedefs() {
+		if !td.isDocumentable() {
+			// left out by the access option
+		} else if td.docBlock() != nil {
+			td.docBlock().generate()
+		} else {
+			docError(td.file(), td.line(),
+				"Undocumented typedef: "+td.fullName())
+		}
+	}
+
+	for _, e := range this.e {
+		if !e.isDocumentable() {
+			// left out by the access option
+		} else if e.docBlock() != nil {
+			e.docBlock().generate()
+		} else if !e.name().isEmpty() {
+			docError(e.file(), e.line(),
+				"Undocumented enum: "+e.fullName())
+		}
+	}
+
 	for _, f := range this.m {
-		if f.docBlock() != nil {
+		if !f.isDocumentable() {
+			// left out by the access option
+		} else if f.docBlock() != nil {
 			f.docBlock().generate()
-		} else if f.super() == nil {
+		} else if f.super() == nil && !f.isDeleted() {
 			docError(f.file(), f.line(),
 				"Undocumented function: "+
 					f.name()+f.arguments())
 		}
 	}
+
+	for _, v := range this.v {
+		if !v.isDocumentable() {
+			// left out by the access option
+		} else if v.docBlock() != nil {
+			v.docBlock().generate()
+		} else {
+			docError(v.file(), v.line(),
+				"Undocumented variable: "+v.fullName())
+		}
+	}
+
+	for _, f := range this.relatedFunctions() {
+		f.docBlock().generate()
+	}
 	this.done = true
 }
 
@@ -169,21 +422,15 @@ func (this *Class) generateOutput() {
 
 func buildHierarchy() {
 	for _, c := range classes {
-		n := c.superclassName
-		i := n.find("<")
-		if i >= 0 {
-			n = n.mid(0, i)
-		}
-		if !n.isEmpty() {
-			p := findClass(n)
-			c.super = p
+		for _, b := range c.b {
+			p := lookupClass(b.n, scopeOf(c.n))
+			b.c = p
 			if p != nil {
 				p.sub = append(p.sub, c)
-			}
-			if c.super == nil {
+			} else {
 				docError(c.f, c.l, "Class "+c.n+
 					" inherits undocumented class "+
-					c.superclassName)
+					b.n)
 			}
 		}
 	}
@@ -207,12 +454,68 @@ func (this Class) members() []*Function {
 	return this.m
 }
 
+/*! Returns the documented free functions which relate to this
+  class, in the order they were seen. */
+
+func (this *Class) relatedFunctions() []*Function {
+	var r []*Function
+	for _, f := range functions {
+		if f.relates() == this && f.docBlock() != nil &&
+			!f.docBlock().isInternal() {
+			r = append(r, f)
+		}
+	}
+	return r
+}
+
 /*! \fn Class * Class::parent() const
 
-  Returns a pointer to the superclass of this class, or a null
-  pointer if this class doesn't inherit anything.
+  Returns a pointer to the first superclass of this class, or a null
+  pointer if this class doesn't inherit any documented class.
 */
 
 func (this Class) parent() *Class {
-	return this.super
+	for _, b := range this.b {
+		if b.c != nil {
+			return b.c
+		}
+	}
+	return nil
+}
+
+/*! Returns the documented classes this class inherits directly, in
+  declaration order. */
+
+func (this Class) parents() []*Class {
+	var r []*Class
+	for _, b := range this.b {
+		if b.c != nil {
+			r = append(r, b.c)
+		}
+	}
+	return r
+}
+
+/*! Returns this class followed by all the classes it inherits,
+  directly or indirectly, nearest first. Each class is listed once,
+  even if it is inherited along several paths. Name lookup from
+  within a class searches these in order.
+*/
+
+func (this *Class) ancestry() []*Class {
+	r := []*Class{this}
+	for i := 0; i < len(r); i++ {
+		for _, p := range r[i].parents() {
+			seen := false
+			for _, o := range r {
+				if o == p {
+					seen = true
+				}
+			}
+			if !seen {
+				r = append(r, p)
+			}
+		}
+	}
+	return r
 }
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/*! \class Config config.h
+  The Config class models a udoc configuration file.
+
+  A configuration file is either udoc.json or udoc.toml. Both contain
+  the same settings under the same names, e.g.
+
+  \code
+    owner = "Example Inc."
+    owner-home = "https://example.com/"
+    inputs = [ "src", "lib" ]
+    output = "doc/api"
+    exclude = [ "third_party", "*_test.cpp" ]
+    source-extensions = [ ".cpp", ".cc" ]
+    header-extensions = [ ".h", ".hpp" ]
+    strip-macros = [ "MYLIB_EXPORT", "Q_OBJECT", "Q_PROPERTY()" ]
+    defines = [ "MYLIB_HAVE_SSL", "MYLIB_VERSION=3" ]
+    formats = [ "html" ]
+    theme = "doc/theme"
+    templates = "doc/templates"
+    inline-reimp = true
+    suppress = [ "Undocumented argument: *" ]
+    access = [ "public", "protected" ]
+  \endcode
+
+  Paths are relative to the directory containing the file. Only a
+  small subset of TOML is understood: string, boolean and string
+  array values, and comments.
+
+  The configuration file in the current directory (or the one named
+  by -config) applies to the whole run. Configuration files found in
+  the input directories override include, exclude, extensions,
+  strip-macros, defines, suppress and access for that directory and its
+  subdirectories. The remaining settings are global and are ignored
+  there.
+*/
+
+type configFile struct {
+	Owner            *string  `json:"owner"`
+	OwnerHome        *string  `json:"owner-home"`
+	Inputs           []string `json:"inputs"`
+	Output           *string  `json:"output"`
+	Include          []string `json:"include"`
+	Exclude          []string `json:"exclude"`
+	SourceExtensions []string `json:"source-extensions"`
+	HeaderExtensions []string `json:"header-extensions"`
+	StripMacros      []string `json:"strip-macros"`
+	Defines          []string `json:"defines"`
+	Formats          []string `json:"formats"`
+	Theme            *string  `json:"theme"`
+	Templates        *string  `json:"templates"`
+	InlineReimp      *bool    `json:"inline-reimp"`
+	Suppress         []string `json:"suppress"`
+	Access           []string `json:"access"`
+}
+
+var configNames = []string{"udoc.json", "udoc.toml"}
+
+var directoryOptions = make(map[string]*optionsT)
+
+var loadedConfigs = make(map[string]bool)
+
+/*! Returns the name of the configuration file in directory \a dir,
+  or an empty string if there is none. */
+
+func findConfig(dir string) string {
+	for _, n := range configNames {
+		p := filepath.Join(dir, n)
+		info, err := os.Stat(p)
+		if err == nil && !info.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+/*! Returns \a path as an absolute path if possible, and \a path
+  itself otherwise. */
+
+func absolutePath(path string) string {
+	a, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return a
+}
+
+/*! Reads and returns the configuration file \a path, or returns an
+  error if it cannot be read or contains unknown settings. */
+
+func readConfig(path string) (*configFile, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(path, ".toml") {
+		b, err = tomlToJSON(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+	}
+	c := &configFile{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	err = d.Decode(c)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return c, nil
+}
+
+/*! Applies the settings in \a c, which was read from a file in \a
+  dir, to this Options object. If \a global is false, settings which
+  apply to the entire run are refused with an error message.
+*/
+
+func (this *optionsT) apply(c *configFile, dir string, global bool) error {
+	if global {
+		if c.Owner != nil {
+			this.owner = *c.Owner
+		}
+		if c.OwnerHome != nil {
+			this.ownerHome = *c.OwnerHome
+		}
+		if c.Inputs != nil {
+			this.inputs = nil
+			for _, in := range c.Inputs {
+				this.inputs = append(this.inputs, filepath.Join(dir, in))
+			}
+		}
+		if c.Output != nil {
+			this.outputDir = filepath.Join(dir, *c.Output)
+		}
+		if c.Formats != nil {
+			this.formats = c.Formats
+		}
+		if c.Theme != nil {
+			this.theme = filepath.Join(dir, *c.Theme)
+		}
+		if c.Templates != nil {
+			this.templates = filepath.Join(dir, *c.Templates)
+		}
+		if c.InlineReimp != nil {
+			this.inlineReimp = *c.InlineReimp
+		}
+	} else if c.Owner != nil || c.OwnerHome != nil || c.Inputs != nil ||
+		c.Output != nil || c.Formats != nil || c.Theme != nil ||
+		c.Templates != nil || c.InlineReimp != nil {
+		log.Printf("Ignoring global settings in configuration file in %s", dir)
+	}
+	if c.Include != nil || c.Exclude != nil {
+		if c.Include != nil {
+			this.include = c.Include
+		}
+		if c.Exclude != nil {
+			this.exclude = c.Exclude
+		}
+		this.patternRoot = dir
+		if global && dir == "." {
+			this.patternRoot = ""
+		}
+	}
+	if c.SourceExtensions != nil {
+		this.sourceExtensions = normalizedExtensions(c.SourceExtensions)
+	}
+	if c.HeaderExtensions != nil {
+		this.headerExtensions = normalizedExtensions(c.HeaderExtensions)
+	}
+	if c.StripMacros != nil {
+		this.stripMacros = c.StripMacros
+	}
+	if c.Defines != nil {
+		this.defines = c.Defines
+	}
+	if c.Suppress != nil {
+		this.suppress = c.Suppress
+	}
+	if c.Access != nil {
+		this.access = c.Access
+	}
+	return this.validate()
+}
+
+/*! Looks for a configuration file in directory \a dir and, if there
+  is one, records a copy of \a parent modified by that file as the
+  options for \a dir and its subdirectories.
+*/
+
+func loadDirectoryConfig(dir string, parent *optionsT) error {
+	path := findConfig(dir)
+	if path == "" || loadedConfigs[absolutePath(path)] {
+		return nil
+	}
+	loadedConfigs[absolutePath(path)] = true
+	c, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+	o := parent.clone()
+	err = o.apply(c, dir, false)
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	log.Printf("Using configuration file %s", path)
+	directoryOptions[filepath.Clean(dir)] = o
+	return nil
+}
+
+/*! Returns the Options which apply to files in directory \a dir:
+  those set by the closest configuration file in \a dir or a parent
+  directory, or the global options if there is none.
+*/
+
+func optionsFor(dir string) *optionsT {
+	d := filepath.Clean(dir)
+	for {
+		o, ok := directoryOptions[d]
+		if ok {
+			return o
+		}
+		p := filepath.Dir(d)
+		if p == d {
+			break
+		}
+		d = p
+	}
+	return options
+}
+
+/*! Returns true if the diagnostic \a text is suppressed by one of
+  the suppress patterns. A pattern suppresses a diagnostic if it
+  matches the diagnostic's text, where "*" matches any sequence of
+  characters, or if the text starts with the pattern.
+*/
+
+func (this *optionsT) suppresses(text estring) bool {
+	for _, p := range this.suppress {
+		if strings.HasPrefix(string(text), p) || wildcardMatch(p, string(text)) {
+			return true
+		}
+	}
+	return false
+}
+
+/*! Returns true if \a s matches \a pattern, in which "*" matches any
+  sequence of characters and all other characters match themselves.
+*/
+
+func wildcardMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
+
+/*! Converts the TOML subset udoc understands in \a b to the
+  equivalent JSON, so that the same decoder can read both formats.
+
+  Each setting is a line of the form key = value, where value is a
+  quoted string, true, false or an array of quoted strings. Arrays
+  may span several lines. Comments start with '#'.
+*/
+
+func tomlToJSON(b []byte) ([]byte, error) {
+	m := make(map[string]interface{})
+	lines := strings.Split(string(b), "\n")
+	for n := 0; n < len(lines); n++ {
+		line := stripTomlComment(lines[n])
+		if line == "" {
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", n+1)
+		}
+		key := strings.Trim(strings.TrimSpace(line[:eq]), "\"")
+		value := strings.TrimSpace(line[eq+1:])
+		start := n
+		for strings.HasPrefix(value, "[") && !strings.HasSuffix(value, "]") &&
+			n+1 < len(lines) {
+			n++
+			value += " " + stripTomlComment(lines[n])
+		}
+		v, err := tomlValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", start+1, err)
+		}
+		m[key] = v
+	}
+	return json.Marshal(m)
+}
+
+/*! Returns \a line without any trailing comment or surrounding
+  whitespace. A '#' inside a quoted string does not start a
+  comment. */
+
+func stripTomlComment(line string) string {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && quoted {
+			i++
+		} else if line[i] == '"' {
+			quoted = !quoted
+		} else if line[i] == '#' && !quoted {
+			line = line[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(line)
+}
+
+/*! Parses the TOML value \a v and returns it as a string, bool or
+  []string. */
+
+func tomlValue(v string) (interface{}, error) {
+	if v == "true" || v == "false" {
+		return v == "true", nil
+	}
+	if strings.HasPrefix(v, "\"") {
+		return strconv.Unquote(v)
+	}
+	if !strings.HasPrefix(v, "[") || !strings.HasSuffix(v, "]") {
+		return nil, fmt.Errorf("cannot parse value %s", v)
+	}
+	r := []string{}
+	rest := strings.TrimSpace(v[1 : len(v)-1])
+	for rest != "" {
+		if !strings.HasPrefix(rest, "\"") {
+			return nil, fmt.Errorf("array elements must be strings")
+		}
+		i := 1
+		for i < len(rest) && rest[i] != '"' {
+			if rest[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(rest) {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		s, err := strconv.Unquote(rest[:i+1])
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, s)
+		rest = strings.TrimSpace(rest[i+1:])
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, ","))
+	}
+	return r, nil
+}
@@ -6,6 +6,8 @@ const (
 	Plain State = iota
 	Argument
 	Introduces
+	Value
+	Relates
 )
 
 type string_dict map[estring]bool
@@ -23,15 +25,24 @@ func (this string_dict) insert(k estring) {
   The DocBlock class represents a single atom of documentation.
 
   A documentation block is written as a C multi-line comment and
-  documents a single class or a single function. DocBlock knows how
+  documents a single class, enum, function or variable. DocBlock knows how
   to generate output for itself.
 */
 
+var docBlocks []*DocBlock
+
 /*!  Constructs a DocBlock from \a sourceFile, which starts at
   \a sourceLine, has source \a text and documents \a function.
 */
 
 func newDocBlockForFunction(sourceFile File, sourceLine int, text estring, function *Function) *DocBlock {
+	// the class page must list the function before the text is parsed
+	i := text.find("\\relates")
+	if i >= 0 {
+		p := newParser(text)
+		p.i = i + 8
+		function.setRelates(p.identifier())
+	}
 	f := &DocBlock{
 		file:      sourceFile,
 		line:      sourceLine,
@@ -41,6 +52,7 @@ func newDocBlockForFunction(sourceFile File, sourceLine int, text estring, funct
 		arguments: make(string_dict),
 	}
 	f.f.setDocBlock(f)
+	docBlocks = append(docBlocks, f)
 	return f
 }
 
@@ -58,6 +70,61 @@ func newDocBlockForClass(sourceFile File, sourceLine int, text estring, classNam
 		arguments: make(string_dict),
 	}
 	f.c.setDocBlock(f)
+	docBlocks = append(docBlocks, f)
+	return f
+}
+
+/*!  Constructs a DocBlock from \a sourceFile, which starts at
+  \a sourceLine, has source \a text and documents \a enum.
+*/
+
+func newDocBlockForEnum(sourceFile File, sourceLine int, text estring, enum *Enum) *DocBlock {
+	f := &DocBlock{
+		file:      sourceFile,
+		line:      sourceLine,
+		e:         enum,
+		t:         text,
+		s:         Plain,
+		arguments: make(string_dict),
+	}
+	f.e.setDocBlock(f)
+	docBlocks = append(docBlocks, f)
+	return f
+}
+
+/*!  Constructs a DocBlock from \a sourceFile, which starts at
+  \a sourceLine, has source \a text and documents \a variable.
+*/
+
+func newDocBlockForVariable(sourceFile File, sourceLine int, text estring, variable *Variable) *DocBlock {
+	f := &DocBlock{
+		file:      sourceFile,
+		line:      sourceLine,
+		v:         variable,
+		t:         text,
+		s:         Plain,
+		arguments: make(string_dict),
+	}
+	f.v.setDocBlock(f)
+	docBlocks = append(docBlocks, f)
+	return f
+}
+
+/*!  Constructs a DocBlock from \a sourceFile, which starts at
+  \a sourceLine, has source \a text and documents \a typedef.
+*/
+
+func newDocBlockForTypedef(sourceFile File, sourceLine int, text estring, typedef *Typedef) *DocBlock {
+	f := &DocBlock{
+		file:      sourceFile,
+		line:      sourceLine,
+		td:        typedef,
+		t:         text,
+		s:         Plain,
+		arguments: make(string_dict),
+	}
+	f.td.setDocBlock(f)
+	docBlocks = append(docBlocks, f)
 	return f
 }
 
@@ -75,6 +142,7 @@ func newDocBlockForIntro(sourceFile File, sourceLine int, text estring, intro *I
 		arguments: make(string_dict),
 	}
 	f.i.setDocBlock(f)
+	docBlocks = append(docBlocks, f)
 	return f
 }
 
@@ -83,6 +151,9 @@ type DocBlock struct {
 	line       int
 	c          *Class
 	f          *Function
+	e          *Enum
+	v          *Variable
+	td         *Typedef
 	i          *Intro
 	t          estring
 	s          State
@@ -96,32 +167,33 @@ type DocBlock struct {
 */
 
 func (this *DocBlock) generate() {
-	if this.t.contains("\\internal") {
+	if this.isInternal() {
 		return
 	}
 	if this.f != nil {
 		this.generateFunctionPreamble()
+	} else if this.e != nil {
+		this.generateEnumPreamble()
+	} else if this.v != nil {
+		this.generateVariablePreamble()
+	} else if this.td != nil {
+		this.generateTypedefPreamble()
 	} else if this.c != nil {
 		this.generateClassPreamble()
 	} else if this.i != nil {
 		this.generateIntroPreamble()
 	}
 
-	n := 0
-	l := this.line
-	i := 0
-	for i < this.t.length() {
-		this.whitespace(&i, &l)
-		n++
-		this.word(&i, l, n)
-	}
-	output.endParagraph()
+	this.generateText()
 	if this.f != nil {
 		super := this.f.super()
 		if super != nil {
 			output.addText("Reimplements ")
 			output.addFunction(super.name()+"().", super)
 			output.endParagraph()
+			if this.isReimp && options.inlineReimp {
+				this.generateInherited()
+			}
 		}
 	}
 	if this.f != nil && !this.isReimp {
@@ -151,45 +223,159 @@ func (this *DocBlock) generate() {
 			i++
 		}
 	}
+	if this.e != nil {
+		for _, v := range this.e.enumValues() {
+			if !v.documented {
+				docError(this.file, this.line, "Undocumented enum value: "+v.n)
+			}
+		}
+	}
 	if this.i != nil && !this.introduces {
 		docError(this.file, this.line, "\\chapter must contain \\introduces")
 	}
 }
 
+/*! Parses the text() and generates output for it, without any
+  preamble. */
+
+func (this *DocBlock) generateText() {
+	n := 0
+	l := this.line
+	i := 0
+	for i < this.t.length() {
+		this.whitespace(&i, &l)
+		if i >= this.t.length() {
+			// trailing whitespace mustn't start a paragraph
+			break
+		}
+		n++
+		this.word(&i, l, n)
+	}
+	output.endParagraph()
+}
+
+/*! Generates the documentation this DocBlock's function inherits
+  using \reimp: the text of the nearest function it reimplements
+  which has documentation of its own. Any errors in that text are
+  reported when that function is documented, so none are reported
+  here.
+*/
+
+func (this *DocBlock) generateInherited() {
+	super := this.f.super()
+	for super != nil {
+		d := super.docBlock()
+		if d != nil && !d.isInternal() && !d.t.contains("\\reimp") {
+			inherited := &DocBlock{
+				f:         super,
+				t:         d.t,
+				s:         Plain,
+				arguments: make(string_dict),
+			}
+			inherited.generateText()
+			return
+		}
+		super = super.super()
+	}
+}
+
+/*! Returns true if this DocBlock is marked \internal, and so should
+  not generate any output. */
+
+func (this *DocBlock) isInternal() bool {
+	return this.t.contains("\\internal")
+}
+
+/*! Returns the first sentence of the text(), without any udoc
+  directives, or an empty string if the first paragraph does not
+  contain any text. This is suitable for summaries such as the
+  index page.
+*/
+
+func (this *DocBlock) firstSentence() estring {
+	var r estring
+	i := 0
+	for i < this.t.length() {
+		newlines := 0
+		for i < this.t.length() && (this.t[i] == 32 || this.t[i] == 9 ||
+			this.t[i] == 13 || this.t[i] == 10) {
+			if this.t[i] == 10 {
+				newlines++
+			}
+			i++
+		}
+		if !r.isEmpty() && newlines > 1 {
+			break
+		}
+		j := i
+		for j < this.t.length() && !(this.t[j] == 32 || this.t[j] == 9 ||
+			this.t[j] == 13 || this.t[j] == 10) {
+			j++
+		}
+		w := this.t.mid(i, j-i)
+		i = j
+		if w.isEmpty() || w.at(0) == '\\' {
+			continue
+		}
+		if !r.isEmpty() {
+			r += " "
+		}
+		r += w
+		if w.endsWith(".") {
+			break
+		}
+	}
+	return r
+}
+
 /*! Outputs boilerplante and genetated text to create a suitable
   headline and lead-in text for this DocBlock's function.
 */
 
 func (this *DocBlock) generateFunctionPreamble() {
 	output.startHeadlineFunction(this.f)
-	addWithClass(this.f.typeStr(), this.f.parent())
-	output.addText(" ")
-	output.addText(this.f.name())
-	a := this.f.arguments()
-	if a == "()" {
-		output.addText(this.f.arguments())
-	} else {
-		s := 0
-		e := 0
-		for e < a.length() {
-			for e < a.length() && a[e] != ',' {
-				e++
-			}
-			addWithClass(a.mid(s, e+1-s), this.f.parent())
-			s = e + 1
-			for a.at(s) == ' ' {
-				output.addSpace()
-				s++
-			}
-			e = s
+	if !this.f.templateParameters().isEmpty() {
+		output.addText("template" + this.f.templateParameters() + " ")
+	}
+	if this.f.access() != "public" {
+		output.addText(this.f.access() + " ")
+	}
+	for _, s := range this.f.specifiers() {
+		if isLeadingSpecifier(s) {
+			output.addText(s + " ")
 		}
 	}
+	if !this.f.typeStr().isEmpty() {
+		addWithClass(this.f.typeStr(), this.f.parent(), this.f.scope())
+		output.addText(" ")
+	}
+	output.addText(this.f.name())
+	addArgumentsWithClass(this.f.arguments(), this.f.parent(), this.f.scope())
 	if this.f.isConst() {
 		output.addText(" const")
 	}
+	for _, s := range this.f.specifiers() {
+		if s.startsWith("-> ") {
+			output.addText(" -> ")
+			addWithClass(s.mid(3, s.length()-3), this.f.parent(), this.f.scope())
+		} else if !isLeadingSpecifier(s) && s != "inline" {
+			output.addText(" " + s)
+		}
+	}
 	output.endParagraph()
 }
 
+/*! Returns true if the function specifier \a s is written before
+  the return type in a headline, e.g. "static" or "[[nodiscard]]",
+  and false if it's written after the argument list, e.g. "noexcept"
+  or "override". "inline" is neither.
+*/
+
+func isLeadingSpecifier(s estring) bool {
+	return s == "static" || s == "virtual" || s == "constexpr" ||
+		s == "consteval" || s.startsWith("explicit") || s.startsWith("[[")
+}
+
 /*! Steps past whitespace, modifying the character index \a i and the
   line number \a l.
 */
@@ -217,27 +403,117 @@ func (this *DocBlock) whitespace(i, l *int) {
 	}
 }
 
-func addWithClass(s estring, in *Class) {
+/*! Adds the argument list \a a, e.g. "(int x, Shape * s)", using
+  addWithClass() for each argument.
+*/
+
+func addArgumentsWithClass(a estring, in *Class, scope estring) {
+	if a == "()" {
+		output.addText(a)
+		return
+	}
+	s := 0
+	e := 0
+	for e < a.length() {
+		for e < a.length() && a[e] != ',' {
+			e++
+		}
+		addWithClass(a.mid(s, e+1-s), in, scope)
+		s = e + 1
+		for a.at(s) == ' ' {
+			output.addSpace()
+			s++
+		}
+		e = s
+	}
+}
+
+/*! Adds the type \a s, which is seen in class \a in (a null
+  pointer for a free function), linking the first class, typedef or
+  enum it names. Class and typedef names are looked up as seen from
+  \a scope, the fully qualified name of \a in or of a free
+  function's namespace, so that classes in the same namespace are
+  found.
+*/
+
+func addWithClass(s estring, in *Class, scope estring) {
 	var c *Class
+	var td *Typedef
 	i := 0
-	for c == nil && i < s.length() {
-		if s[i] >= 'A' && s[i] <= 'Z' {
+	for c == nil && td == nil && i < s.length() {
+		if (s[i] >= 'A' && s[i] <= 'Z') || (s[i] >= 'a' && s[i] <= 'z') {
 			j := i
 			for (s.at(j) >= 'A' && s.at(j) <= 'Z') ||
 				(s.at(j) >= 'a' && s.at(j) <= 'z') ||
-				(s.at(j) >= '0' && s.at(j) <= '9') {
+				(s.at(j) >= '0' && s.at(j) <= '9') ||
+				s.at(j) == '_' ||
+				(s.at(j) == ':' && s.at(j+1) == ':') {
+				if s.at(j) == ':' {
+					j++
+				}
 				j++
 			}
-			c = findClass(s.mid(i, j-i))
+			// the longest prefix which names a typedef or class
+			// wins, so that EString::Encoding links to EString
+			n := s.mid(i, j-i)
+			for c == nil && td == nil && !n.isEmpty() {
+				td = lookupTypedef(n, scope)
+				if td != nil && !td.isDocumented() {
+					td = nil
+				}
+				if td == nil && ((n.at(0) >= 'A' && n.at(0) <= 'Z') ||
+					n.contains("::")) {
+					c = lookupClass(n, scope)
+				}
+				n = scopeOf(n)
+			}
 			i = j
 		}
 		i++
 	}
 	if c != nil && c != in {
 		output.addClass(s, c)
-	} else {
-		output.addText(s)
+		return
+	}
+	if c == nil {
+		e := enumInType(s, in)
+		if e != nil {
+			output.addEnum(s, e)
+			return
+		}
+	}
+	if td != nil {
+		output.addTypedef(s, td)
+		return
+	}
+	output.addText(s)
+}
+
+/*! Returns the Enum named by the type in \a s, which is seen in
+  class \a in, or a null pointer if \a s does not name an enum.
+*/
+
+func enumInType(s estring, in *Class) *Enum {
+	i := 0
+	for i < s.length() {
+		if (s[i] >= 'A' && s[i] <= 'Z') || (s[i] >= 'a' && s[i] <= 'z') {
+			j := i
+			for (s.at(j) >= 'A' && s.at(j) <= 'Z') ||
+				(s.at(j) >= 'a' && s.at(j) <= 'z') ||
+				(s.at(j) >= '0' && s.at(j) <= '9') ||
+				s.at(j) == '_' || s.at(j) == ':' {
+				j++
+			}
+			n := s.mid(i, j-i)
+			e := findEnumInScope(n, in)
+			if e != nil && e.name() == unqualified(n) {
+				return e
+			}
+			i = j
+		}
+		i++
 	}
+	return nil
 }
 
 /*! Sets the DocBlock to state \a newState based on directive \a w,
@@ -316,6 +592,13 @@ func (this *DocBlock) word(i *int, l, n int) {
 		} else {
 			docError(this.file, l, "\\a is only defined function documentation")
 		}
+	} else if w == "\\value" {
+		if this.e != nil {
+			output.endParagraph()
+			this.setState(Value, w, l)
+		} else {
+			docError(this.file, l, "\\value is only valid after \\enum")
+		}
 	} else if w == "\\introduces" {
 		if this.i != nil {
 			this.setState(Introduces, w, l)
@@ -323,8 +606,17 @@ func (this *DocBlock) word(i *int, l, n int) {
 			docError(this.file, l, "\\introduces is only valid after \\chapter")
 		}
 		this.introduces = true
+	} else if w == "\\relates" {
+		if this.f == nil || this.f.parent() != nil {
+			docError(this.file, l,
+				"\\relates is only meaningful for free functions")
+		} else {
+			this.setState(Relates, w, l)
+		}
 	} else if w == "\\overload" {
 		this.overload(l, n)
+	} else if w == "\\reimp" {
+		this.reimp(l)
 	} else if w == "\\code" {
 		this.code(i)
 	} else if w == "\\warning" {
@@ -351,7 +643,7 @@ func (this *DocBlock) word(i *int, l, n int) {
 func (this *DocBlock) plainWord(w estring, l int) {
 	if this.s == Introduces {
 		newSingleton(this.file, l, w)
-		c := findClass(w)
+		c := lookupClass(w, "")
 		if c != nil {
 			this.i.addClass(c)
 		} else {
@@ -359,6 +651,13 @@ func (this *DocBlock) plainWord(w estring, l int) {
 		}
 		return
 	}
+	if this.s == Relates {
+		if this.f.relates() == nil {
+			docError(this.file, l, "Cannot find class: "+w)
+		}
+		this.setState(Plain, "(after class name)", l)
+		return
+	}
 	// find the last character of the word proper
 	last := w.length() - 1
 	for last > 0 && (w[last] == ',' || w[last] == '.' ||
@@ -382,6 +681,17 @@ func (this *DocBlock) plainWord(w estring, l int) {
 		output.addArgument(w)
 		this.setState(Plain, "(after argument name)", l)
 		return
+	} else if this.s == Value {
+		name := w.mid(0, last+1)
+		v := this.e.value(name)
+		if v != nil {
+			v.documented = true
+		} else {
+			docError(this.file, l, "No such enum value: "+name)
+		}
+		output.addEnumValue(w, this.e)
+		this.setState(Plain, "(after enum value)", l)
+		return
 	} else if w.at(last) == '(' {
 		// is the word a plausible function name?
 		i := 0
@@ -395,42 +705,96 @@ func (this *DocBlock) plainWord(w estring, l int) {
 			scope := this.c
 			if this.f != nil && scope == nil {
 				scope = this.f.parent()
+			} else if this.e != nil && scope == nil {
+				scope = this.e.parent()
+			} else if this.v != nil && scope == nil {
+				scope = this.v.parent()
+			} else if this.td != nil && scope == nil {
+				scope = this.td.parent()
 			}
 			if name.contains(":") {
 				link = findFunction(name, "", false)
-			} else {
-				parent := scope
-				for parent != nil && link == nil {
+				if link == nil {
+					var s estring
+					if scope != nil {
+						s = scope.name()
+					} else if this.f != nil {
+						s = this.f.scope()
+					} else if this.td != nil {
+						s = this.td.scope()
+					}
+					c := lookupClass(scopeOf(name), s)
+					if c != nil {
+						link = findFunction(c.name()+"::"+unqualified(name), "", false)
+					}
+				}
+			} else if scope != nil {
+				for _, parent := range scope.ancestry() {
 					tmp := parent.name() + "::" + name
 					link = findFunction(tmp, "", false)
 					if link != nil {
 						name = tmp
-					} else {
-						parent = parent.parent()
+						break
+					}
+				}
+			} else if this.f != nil {
+				// a free function: look in its namespace and outward
+				s := this.f.scope()
+				for link == nil {
+					link = findFunction(qualified(s, name), "", false)
+					if s.isEmpty() {
+						break
 					}
+					s = scopeOf(s)
 				}
 			}
 			if scope != nil && link == nil && name != "main" {
 				docError(this.file, l,
 					"No link target for "+name+
 						"() (in class "+scope.name()+")")
-			} else if link != nil && link != this.f {
+			} else if link != nil && link != this.f && link.isDocumentable() {
 				output.addFunction(w, link)
 				return
 			}
 		}
-	} else if w.at(0) >= 'A' && w.at(0) <= 'Z' &&
+	} else if ((w.at(0) >= 'A' && w.at(0) <= 'Z') || w.contains("::")) &&
 		(this.c == nil || w.mid(0, last+1) != this.c.name()) {
 		// is it a plausible class name? or enum, or enum value?
-		link := findClass(w.mid(0, last+1))
 		thisClass := this.c
 		if this.f != nil && this.c == nil {
 			thisClass = this.f.parent()
+		} else if this.e != nil && this.c == nil {
+			thisClass = this.e.parent()
+		} else if this.v != nil && this.c == nil {
+			thisClass = this.v.parent()
+		} else if this.td != nil && this.c == nil {
+			thisClass = this.td.parent()
 		}
+		var scope estring
+		if thisClass != nil {
+			scope = thisClass.name()
+		} else if this.f != nil {
+			scope = this.f.scope()
+		} else if this.td != nil {
+			scope = this.td.scope()
+		}
+		link := lookupClass(w.mid(0, last+1), scope)
 		if link != nil && link != thisClass {
 			output.addClass(w, link)
 			return
 		}
+		if link == nil {
+			e := findEnumInScope(w.mid(0, last+1), thisClass)
+			if e != nil && e != this.e {
+				output.addEnum(w, e)
+				return
+			}
+			td := lookupTypedef(w.mid(0, last+1), scope)
+			if td != nil && td != this.td && td.isDocumented() {
+				output.addTypedef(w, td)
+				return
+			}
+		}
 		// here, we could look to see if that looks _very_ much like a
 		// class name, e.g. contains all alphanumerics and at least
 		// one "::", and give an error about undocumented classes if
@@ -497,7 +861,7 @@ func (this *DocBlock) section(i *int, sect int) {
  */
 func (this *DocBlock) code(i *int) {
 	p := newParser(this.t[*i:])
-	code := p.textUntil("\\endcode")
+	code := p.rawTextUntil("\\endcode")
 
 	startSplit := 0
 	for _, c := range code {
@@ -571,20 +935,62 @@ func (this *DocBlock) overload(l, n int) {
 	}
 }
 
+/*! Handles the "\reimp" directive, which says that the function's
+  documentation is inherited from the function it reimplements. \a l
+  is the line number where the directive was seen.
+*/
+
+func (this *DocBlock) reimp(l int) {
+	if this.f == nil {
+		docError(this.file, l,
+			"\\reimp is only meaningful for functions")
+	} else if this.f.super() == nil {
+		docError(this.file, l,
+			"\\reimp used, but "+this.f.name()+
+				"() does not reimplement anything")
+	} else {
+		this.isReimp = true
+	}
+}
+
 /*! Generates the routine text that introduces the documentation for
   each class, e.g. what the class inherits.
 */
 
 func (this *DocBlock) generateClassPreamble() {
 	output.startHeadlineClass(this.c)
-	output.addText("Class ")
-	output.addText(this.c.name())
+	kind := this.c.kind()
+	kind = kind.mid(0, 1).upper() + kind.mid(1, kind.length())
+	tp := this.c.templateParameters()
+	if !tp.isEmpty() && tp != "<>" {
+		output.addText(kind + " template ")
+		output.addText(this.c.name() + tp)
+	} else {
+		output.addText(kind + " ")
+		output.addText(this.c.name())
+	}
 	output.addText(".")
 	output.endParagraph()
+	this.generateTemplateRelations()
 	p := false
-	if this.c.parent() != nil {
+	bases := this.c.bases()
+	if len(bases) > 0 {
 		output.addText("Inherits ")
-		output.addClass(this.c.parent().name(), this.c.parent())
+		for idx, b := range bases {
+			if b.access != "public" {
+				output.addText(b.access + " ")
+			}
+			if b.c != nil {
+				output.addClass(b.c.name(), b.c)
+			} else {
+				output.addText(b.n)
+			}
+			if idx == len(bases)-2 {
+				output.addText(" and ")
+			} else if idx < len(bases)-2 {
+				output.addText(", ")
+			}
+		}
 		p = true
 	}
 
@@ -612,8 +1018,12 @@ func (this *DocBlock) generateClassPreamble() {
 		output.endParagraph()
 	}
 
+	this.generateMemberSummary()
+
+	// a struct or union may be plain data
 	members := this.c.members()
-	if len(members) == 0 {
+	if len(members) == 0 &&
+		(this.c.kind() == "class" || len(this.c.variables()) == 0) {
 		docError(this.file, this.line,
 			"Class "+this.c.name()+" has no member functions")
 		return
@@ -625,6 +1035,201 @@ func (this *DocBlock) generateClassPreamble() {
 	}
 }
 
+/*! Generates a paragraph naming the primary template this class
+  specializes, or the documented specializations of this class
+  template, if there are any.
+*/
+
+func (this *DocBlock) generateTemplateRelations() {
+	if t := this.c.specializes(); t != nil {
+		output.addText("Specializes ")
+		output.addClass(t.name()+".", t)
+		output.endParagraph()
+	}
+	specializations := this.c.specializations()
+	if len(specializations) == 0 {
+		return
+	}
+	output.addText("Specialized by ")
+	for idx, s := range specializations {
+		if idx == len(specializations)-1 {
+			output.addClass(s.name()+".", s)
+		} else if idx == len(specializations)-2 {
+			output.addClass(s.name(), s)
+			output.addText(" and ")
+		} else {
+			output.addClass(s.name()+",", s)
+			output.addText(" ")
+		}
+	}
+	output.endParagraph()
+}
+
+/*! Generates the routine text that introduces the documentation for
+  an enum, namely its name and values.
+*/
+
+func (this *DocBlock) generateEnumPreamble() {
+	output.startHeadlineEnum(this.e)
+	output.addText("enum ")
+	output.addText(this.e.fullName())
+	output.endParagraph()
+}
+
+/*! Generates the routine text that introduces the documentation for
+  a member variable, namely its declaration.
+*/
+
+func (this *DocBlock) generateVariablePreamble() {
+	output.startHeadlineVariable(this.v)
+	if this.v.access() != "public" {
+		output.addText(this.v.access() + " ")
+	}
+	for _, s := range this.v.specifiers() {
+		output.addText(s + " ")
+	}
+	addWithClass(this.v.typeStr(), this.v.parent(), this.v.parent().name())
+	output.addText(" ")
+	output.addText(this.v.fullName())
+	if !this.v.initializer().isEmpty() {
+		init := this.v.initializer()
+		if !init.startsWith("{") {
+			output.addText(" =")
+		}
+		output.addText(" " + init)
+	}
+	output.endParagraph()
+}
+
+/*! Generates the routine text that introduces the documentation for
+  a typedef, namely its declaration.
+*/
+
+func (this *DocBlock) generateTypedefPreamble() {
+	output.startHeadlineTypedef(this.td)
+	if this.td.access() != "public" {
+		output.addText(this.td.access() + " ")
+	}
+	if !this.td.templateParameters().isEmpty() {
+		output.addText("template" + this.td.templateParameters() + " ")
+	}
+	pre, post := this.td.declarator()
+	if this.td.isAlias() {
+		output.addText("using " + this.td.fullName() + " = ")
+	} else {
+		output.addText("typedef ")
+		addWithClass(pre, this.td.parent(), this.td.scope())
+		output.addText(this.td.fullName())
+	}
+	if post.startsWith(")(") {
+		// a pointer to a function
+		output.addText(")")
+		addArgumentsWithClass(post.mid(1, post.length()-1),
+			this.td.parent(), this.td.scope())
+	} else if !post.isEmpty() {
+		addWithClass(post, this.td.parent(), this.td.scope())
+	}
+	output.endParagraph()
+}
+
+/*! Generates a summary of the class's documented nested classes,
+  typedefs, member functions and variables, with separate paragraphs
+  for each access level, e.g. "Public functions: virtual area(),
+  setName() and kind()."
+*/
+
+func (this *DocBlock) generateMemberSummary() {
+	nested := this.c.nestedClasses()
+	if len(nested) > 0 {
+		output.addText("Nested classes: ")
+		for idx, n := range nested {
+			output.addClass(unqualified(n.name()), n)
+			if idx == len(nested)-1 {
+				output.addText(".")
+			} else if idx == len(nested)-2 {
+				output.addText(" and ")
+			} else {
+				output.addText(", ")
+			}
+		}
+		output.endParagraph()
+	}
+	for _, access := range []estring{"public", "protected", "private"} {
+		level := access.mid(0, 1).upper() + access.mid(1, access.length()-1)
+		var types []*Typedef
+		for _, td := range this.c.typedefs() {
+			if td.access() == access && td.isDocumentable() &&
+				td.isDocumented() {
+				types = append(types, td)
+			}
+		}
+		generateTypedefList(level+" types: ", types)
+		var members []*Function
+		for _, f := range this.c.members() {
+			if f.access() == access && f.isDocumentable() &&
+				f.docBlock() != nil && !f.docBlock().isInternal() {
+				members = append(members, f)
+			}
+		}
+		if len(members) > 0 {
+			output.addText(level + " functions: ")
+			for idx, f := range members {
+				if f.isPureVirtual() {
+					output.addText("pure virtual ")
+				} else if f.isVirtual() {
+					output.addText("virtual ")
+				} else if f.isStatic() {
+					output.addText("static ")
+				}
+				output.addFunction(unqualified(f.name())+"()", f)
+				if idx == len(members)-1 {
+					output.addText(".")
+				} else if idx == len(members)-2 {
+					output.addText(" and ")
+				} else {
+					output.addText(", ")
+				}
+			}
+			output.endParagraph()
+		}
+		this.generateVariableSummary(access, level)
+	}
+	generateFunctionList("Related functions: ", this.c.relatedFunctions())
+}
+
+/*! Generates the paragraph listing the class's documented member
+  variables whose access level is \a access, e.g. "Public variables:
+  static count and name." \a level is \a access capitalized.
+*/
+
+func (this *DocBlock) generateVariableSummary(access, level estring) {
+	var variables []*Variable
+	for _, v := range this.c.variables() {
+		if v.access() == access && v.isDocumentable() &&
+			v.docBlock() != nil && !v.docBlock().isInternal() {
+			variables = append(variables, v)
+		}
+	}
+	if len(variables) == 0 {
+		return
+	}
+	output.addText(level + " variables: ")
+	for idx, v := range variables {
+		if v.isStatic() {
+			output.addText("static ")
+		}
+		output.addVariable(v.name(), v)
+		if idx == len(variables)-1 {
+			output.addText(".")
+		} else if idx == len(variables)-2 {
+			output.addText(" and ")
+		} else {
+			output.addText(", ")
+		}
+	}
+	output.endParagraph()
+}
+
 /*! Generates routine text to introduce an introduction. Yay! */
 
 func (this *DocBlock) generateIntroPreamble() {
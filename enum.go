@@ -0,0 +1,211 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+)
+
+/*! \class Enum enum.h
+  The Enum class models a C++ enum declared in a class.
+
+  An Enum belongs to a Class, has a list of values (each with an
+  optional initializer), an origin file and line, and should have a
+  DocBlock, written using "\enum Class::Name". The DocBlock documents
+  each value using "\value".
+*/
+
+type Enum struct {
+	c      *Class
+	n      estring
+	f      File
+	l      int
+	values []*enumValue
+	acc    estring
+	db     *DocBlock
+}
+
+/*! The enumValue type models one value of an Enum: its name, its
+  initializer (empty if it has none) and whether it has been
+  documented. */
+
+type enumValue struct {
+	n          estring
+	init       estring
+	documented bool
+}
+
+/*! Constructs an Enum named \a name in class \a c, declared at \a
+  originLine of \a originFile, and adds it to \a c. An anonymous enum
+  has an empty \a name.
+*/
+
+func newEnum(c *Class, name estring, originFile File, originLine int) *Enum {
+	if false {
+		log.Printf("New enum: %s::%s", c.name(), name)
+	}
+	e := &Enum{
+		c: c,
+		n: name,
+		f: originFile,
+		l: originLine,
+	}
+	c.insertEnum(e)
+	return e
+}
+
+/*! Returns the class this enum is declared in. */
+
+func (this Enum) parent() *Class {
+	return this.c
+}
+
+/*! Returns the enum's unqualified name, which is empty for an
+  anonymous enum. */
+
+func (this Enum) name() estring {
+	return this.n
+}
+
+/*! Returns the enum's fully qualified name, e.g. "Shape::Kind". */
+
+func (this Enum) fullName() estring {
+	return this.c.name() + "::" + this.n
+}
+
+func (this Enum) file() File {
+	return this.f
+}
+
+func (this Enum) line() int {
+	return this.l
+}
+
+func (this Enum) docBlock() *DocBlock {
+	return this.db
+}
+
+func (this *Enum) setDocBlock(d *DocBlock) {
+	this.db = d
+}
+
+/*! Records the \a access level of the class section this enum is
+  declared in. */
+
+func (this *Enum) setAccess(access estring) {
+	this.acc = access
+}
+
+/*! Returns this enum's access level, "public", "protected" or
+  "private". An enum whose declaration udoc hasn't seen is presumed
+  to be public. */
+
+func (this Enum) access() estring {
+	if this.acc.isEmpty() {
+		return "public"
+	}
+	return this.acc
+}
+
+/*! Returns true if this enum's access level is one udoc is
+  configured to document, and false if it should be left out
+  entirely. */
+
+func (this Enum) isDocumentable() bool {
+	dir := "."
+	if this.f != nil {
+		dir = filepath.Dir(string(this.f.Name()))
+	}
+	return optionsFor(dir).documents(this.access())
+}
+
+/*! Adds the value \a v, whose initializer is \a init, to this enum.
+  \a init is empty if \a v has no initializer.
+*/
+
+func (this *Enum) addValue(v, init estring) {
+	this.values = append(this.values, &enumValue{n: v, init: init})
+}
+
+/*! Returns the values of this enum, in declaration order. */
+
+func (this Enum) enumValues() []*enumValue {
+	return this.values
+}
+
+/*! Returns true if \a v is one of this enum's values, and false if
+  not. */
+
+func (this Enum) hasValue(v estring) bool {
+	return this.value(v) != nil
+}
+
+func (this Enum) value(v estring) *enumValue {
+	for _, ev := range this.values {
+		if ev.n == v {
+			return ev
+		}
+	}
+	return nil
+}
+
+/*! Returns the anchor (sans '#') corresponding to this enum, which
+  output backends use to link to it within its class's page.
+*/
+
+func (this Enum) anchor() estring {
+	return "enum-" + this.n
+}
+
+/*! Returns a pointer to the Enum whose qualified name is \a name as
+  seen from namespace \a ns, or a null pointer if there is no such
+  enum.
+*/
+
+func findEnum(name, ns estring) *Enum {
+	if scopeOf(name).isEmpty() {
+		return nil
+	}
+	c := lookupClass(scopeOf(name), ns)
+	if c == nil {
+		return nil
+	}
+	return c.enumNamed(unqualified(name))
+}
+
+/*! Looks for an enum or enum value called \a name visible from class
+  \a scope: \a name may be qualified, or else it is looked up in \a
+  scope and all its superclasses. Returns the enum, or a null pointer if
+  none is found.
+*/
+
+func findEnumInScope(name estring, scope *Class) *Enum {
+	if name.contains("::") {
+		var s estring
+		if scope != nil {
+			s = scope.name()
+		}
+		c := lookupClass(scopeOf(name), s)
+		if c == nil {
+			return nil
+		}
+		name = unqualified(name)
+		e := c.enumNamed(name)
+		if e == nil {
+			e = c.enumWithValue(name)
+		}
+		return e
+	}
+	if scope == nil {
+		return nil
+	}
+	for _, c := range scope.ancestry() {
+		e := c.enumNamed(name)
+		if e == nil {
+			e = c.enumWithValue(name)
+		}
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
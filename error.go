@@ -2,12 +2,20 @@ package main
 
 import (
 	"fmt"
+	"path/filepath"
 )
 
+/*! Reports the error \a text at \a line of \a f, unless the options
+  for \a f suppress it.
+*/
+
 func docError(f File, line int, text estring) {
 	if f == nil {
 		return
 	}
+	if optionsFor(filepath.Dir(string(f.Name()))).suppresses(text) {
+		return
+	}
 
 	fmt.Printf("%s:%d: %s\n", f.Name(), line, text)
 }
@@ -36,6 +36,30 @@ func (this estring) lower() estring {
 	return result
 }
 
+/*! Returns a copy of this string where all lower-case letters (a-z -
+  this is ASCII only) have been changed to upper case. */
+
+func (this estring) upper() estring {
+	s := []byte(this)
+	for i, c := range s {
+		if c >= 'a' && c <= 'z' {
+			s[i] = c - 32
+		}
+	}
+	return estring(s)
+}
+
+/*! Splits this string at each occurrence of \a sep and returns the
+  parts. An empty string yields a single empty part. */
+
+func (this estring) split(sep byte) estringlist {
+	var r estringlist
+	for _, s := range strings.Split(string(this), string(sep)) {
+		r = append(r, estring(s))
+	}
+	return r
+}
+
 func (this estring) isEmpty() bool {
 	return this == ""
 }
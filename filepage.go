@@ -0,0 +1,122 @@
+package main
+
+import (
+	"sort"
+)
+
+/*! \class FilePage filepage.h
+  The FilePage class models the page which documents the free
+  functions and typedefs in the global namespace that are defined in
+  one source file.
+
+  Free functions and typedefs in a namespace are documented on the
+  namespace's page instead, and functions documented with "\relates"
+  on the page of the class they relate to.
+*/
+
+type FilePage struct {
+	n         estring
+	functions []*Function
+	types     []*Typedef
+}
+
+/*! Returns the name of the source file this page documents. */
+
+func (this FilePage) name() estring {
+	return this.n
+}
+
+/*! Returns the base name of this page. */
+
+func (this FilePage) pageName() estring {
+	return filePageName(this.n)
+}
+
+/*! Returns the base name of the page documenting the free functions
+  in source file \a name, e.g. "file-src-util-cpp" for
+  "src/util.cpp". */
+
+func filePageName(name estring) estring {
+	n := pageName(name)
+	var r estring
+	for i := 0; i < n.length(); i++ {
+		if n[i] == '/' || n[i] == '.' || n[i] == '\\' {
+			r += "-"
+		} else {
+			r += estring(n[i])
+		}
+	}
+	return "file-" + r
+}
+
+/*! Returns a list of the source files which define documented free
+  functions which don't relate to any class, or declare documented
+  typedefs, in the global namespace, sorted by name. */
+
+func documentedFiles() []*FilePage {
+	var r []*FilePage
+	for _, f := range functions {
+		if f.parent() != nil || f.namespace() != nil || f.relates() != nil ||
+			f.docBlock() == nil || f.docBlock().isInternal() {
+			continue
+		}
+		p := filePageIn(&r, f.file().Name())
+		p.functions = append(p.functions, f)
+	}
+	for _, td := range typedefs {
+		if td.parent() != nil || td.namespace() != nil || !td.isDocumented() {
+			continue
+		}
+		p := filePageIn(&r, td.file().Name())
+		p.types = append(p.types, td)
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].n < r[j].n
+	})
+	return r
+}
+
+/*! Returns the page for the source file \a name in \a pages,
+  appending a new one if there is none. */
+
+func filePageIn(pages *[]*FilePage, name estring) *FilePage {
+	for _, p := range *pages {
+		if p.n == name {
+			return p
+		}
+	}
+	p := &FilePage{n: name}
+	*pages = append(*pages, p)
+	return p
+}
+
+/*! This static function generates a page for each source file which
+  defines documented free functions or typedefs in the global
+  namespace.
+*/
+
+func outputFiles() {
+	for _, p := range documentedFiles() {
+		p.generateOutput()
+	}
+}
+
+/*! Generates the page for this file, which lists and documents its
+  typedefs and free functions. */
+
+func (this *FilePage) generateOutput() {
+	output.startHeadlineFile(this)
+	output.addText("File ")
+	output.addText(this.n)
+	output.addText(".")
+	output.endParagraph()
+
+	generateTypedefList("Contains the types ", this.types)
+	generateFunctionList("Contains the functions ", this.functions)
+	for _, td := range this.types {
+		td.docBlock().generate()
+	}
+	for _, f := range this.functions {
+		f.docBlock().generate()
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"path/filepath"
 )
 
 type Function struct {
@@ -15,6 +16,10 @@ type Function struct {
 	db   *DocBlock
 	ol   bool
 	cn   bool
+	acc  estring
+	spec estringlist
+	rel  estring
+	tp   estring
 }
 
 func (this Function) parent() *Class {
@@ -44,7 +49,162 @@ func (this Function) docBlock() *DocBlock {
 func (this *Function) setDocBlock(d *DocBlock) {
 	this.db = d
 }
+
+/*! Records what the class declaration says about this function:
+  its \a access level ("public", "protected" or "private") and its
+  \a specifiers, e.g. "static", "virtual", "explicit", "constexpr",
+  "[[nodiscard]]", "&&", "noexcept", "-> int" (a trailing return
+  type), "override", "final", "= 0", "= default" or "= delete".
+*/
+
+func (this *Function) setDeclaration(access estring, specifiers estringlist) {
+	this.acc = access
+	this.spec = specifiers
+}
+
+/*! Returns this function's access level. A function which was not
+  seen in a class declaration is presumed to be public. */
+
+func (this Function) access() estring {
+	if this.acc.isEmpty() {
+		return "public"
+	}
+	return this.acc
+}
+
+/*! Returns the specifiers given in the class declaration, in the
+  order they were written. */
+
+func (this Function) specifiers() estringlist {
+	return this.spec
+}
+
+func (this Function) isStatic() bool {
+	return this.spec.contains("static")
+}
+
+/*! Returns true if this function is declared virtual, pure virtual
+  or override. */
+
+func (this Function) isVirtual() bool {
+	return this.spec.contains("virtual") || this.spec.contains("override") ||
+		this.isPureVirtual()
+}
+
+func (this Function) isPureVirtual() bool {
+	return this.spec.contains("= 0")
+}
+
+func (this Function) isDeleted() bool {
+	return this.spec.contains("= delete")
+}
+
+/*! Returns true if this function's access level is one udoc is
+  configured to document, and false if it should be left out
+  entirely. */
+
+func (this Function) isDocumentable() bool {
+	dir := "."
+	if this.f != nil {
+		dir = filepath.Dir(string(this.f.Name()))
+	}
+	return optionsFor(dir).documents(this.access())
+}
+
+/*! Records that this function is a template with the parameter
+  list \a tp, e.g. "<typename T>". */
+
+func (this *Function) setTemplateParameters(tp estring) {
+	this.tp = tp
+}
+
+/*! Returns the function's own template parameter list, or an empty
+  string if it is not a function template. The parameters of a class
+  template are not included. */
+
+func (this Function) templateParameters() estring {
+	return this.tp
+}
+
+/*! Notes that this free function is documented with "\relates \a
+  name", so that it is listed on the page of class \a name. */
+
+func (this *Function) setRelates(name estring) {
+	this.rel = name
+}
+
+/*! Returns a pointer to the class this free function relates to, or
+  a null pointer if it doesn't relate to any class (or the class is
+  unknown). Member functions don't relate to any class. */
+
+func (this Function) relates() *Class {
+	if this.c != nil || this.rel.isEmpty() {
+		return nil
+	}
+	return lookupClass(this.rel, scopeOf(this.n))
+}
+
+/*! Returns the namespace a free function is declared in, or a null
+  pointer if it is a member function or in the global namespace. */
+
+func (this Function) namespace() *Namespace {
+	if this.c != nil {
+		return nil
+	}
+	return findNamespace(scopeOf(this.n))
+}
+
+/*! Returns the fully qualified name of the scope in which names
+  used by this function are looked up: its class for a member
+  function, or its namespace for a free function. */
+
+func (this Function) scope() estring {
+	if this.c != nil {
+		return this.c.name()
+	}
+	return scopeOf(this.n)
+}
+
+/*! Returns the base name of the page on which this function is
+  documented: its class's page for a member function, the page of
+  the class it relates to, its namespace's page, or else the page of
+  the file it is defined in. */
+
+func (this Function) pageName() estring {
+	if this.c != nil {
+		return this.c.pageName()
+	}
+	if this.relates() != nil {
+		return this.relates().pageName()
+	}
+	if this.namespace() != nil {
+		return this.namespace().pageName()
+	}
+	return filePageName(this.f.Name())
+}
+
+/*! Returns a pointer to the function this one reimplements: the
+  function in the nearest base class which has the same name,
+  argument types and constness, and is virtual (or whose declaration
+  udoc hasn't seen). Returns a null pointer if this function doesn't
+  reimplement anything.
+
+  buildHierarchy() must have been called first.
+*/
+
 func (this Function) super() *Function {
+	if this.c == nil {
+		return nil
+	}
+	n := unqualified(this.n)
+	for _, c := range this.c.ancestry()[1:] {
+		for _, f := range c.members() {
+			if unqualified(f.n) == n && f.a == this.a && f.cn == this.cn &&
+				(f.isVirtual() || f.acc.isEmpty()) {
+				return f
+			}
+		}
+	}
 	return nil
 }
 
@@ -81,9 +241,12 @@ func findFunction(name, arguments estring, constness bool) *Function {
 }
 
 /*! \class Function function.h
-  The Function class models a member function.
+  The Function class models a member function or a free function.
 
-  Member functions are the only functions in udoc's world.
+  A free function has no parent(); its name is qualified by its
+  namespace, if any. It is documented on its namespace's page, or
+  on the page of its source file if it is in the global namespace,
+  unless "\relates" lists it on a class's page.
 
   Each function has a file() and line() number, which consequently are
   the ones in the class declaration, and it should have a docBlock().
@@ -93,10 +256,14 @@ func findFunction(name, arguments estring, constness bool) *Function {
 var functions []*Function
 
 /*!  Constructs a function whose return type is \a type, whose full
-  name (including class) is \a name, whose arguments are \a
-  arguments, and with \a constness. \a originFile and \a originLine
-  point to the function's defining source, which will be used in any
-  error messages.
+  name (including class or namespace) is \a name, whose arguments
+  are \a arguments, and with \a constness. \a originFile and \a
+  originLine point to the function's defining source, which will be
+  used in any error messages.
+
+  If \a name is unqualified or qualified by a known namespace, the
+  function is a free function; otherwise it is a member of the class
+  named by the qualifier.
 */
 
 func newFunction(typeStr, name, arguments estring, constness bool, originFile File, originLine int) *Function {
@@ -112,20 +279,17 @@ func newFunction(typeStr, name, arguments estring, constness bool, originFile Fi
 
 	functions = append(functions, f)
 
-	i := name.length() - 1
-	for i > 0 && name[i] != ':' {
-		i--
-	}
-	if i == 0 || name[i-1] != ':' {
-		// bad. error. how?
-		return nil
-	}
 	f.n = name
 	f.a = typesOnly(arguments)
 	f.args = arguments
-	f.c = findClass(name.mid(0, i-1))
+	scope := scopeOf(name)
+	if scope.isEmpty() ||
+		(findClass(scope) == nil && findNamespace(scope) != nil) {
+		return f
+	}
+	f.c = findClass(scope)
 	if f.c == nil {
-		f.c = newClass(name.mid(0, i-1), nil, 0)
+		f.c = newClass(scope, nil, 0)
 	}
 	f.c.insert(f)
 	return f
@@ -212,3 +376,22 @@ func (this Function) hasOverload() bool {
 func (this *Function) setOverload() {
 	this.ol = true
 }
+
+/*! Returns the anchor (sans '#') corresponding to this function,
+  which output backends use to link to it within its page.
+*/
+
+func (this Function) anchor() estring {
+	fn := this.name()
+	i := fn.length()
+	for i > 0 && fn.at(i) != ':' {
+		i--
+	}
+	if i > 0 {
+		fn = fn.mid(i+1, len(fn)-i+1)
+	}
+	if fn.startsWith("~") {
+		fn = "destructor"
+	}
+	return fn
+}
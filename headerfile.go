@@ -3,15 +3,18 @@ package main
 import (
 	"io/ioutil"
 	"log"
+	"path/filepath"
 )
 
 /*! \class HeaderFile headerfile.h
   The HeaderFile class models a header file.
 
   The HeaderFile file is viewed as a collection of class { ... }
-  statements, each of which is scanned for member functions and
-  superclass names. Other content is ignored (for now - enums may
-  one day be handled).
+  statements, each of which is scanned for member functions,
+  member variables and superclass names, and for enums and typedefs,
+  plus typedefs at namespace level. Namespace blocks are tracked so
+  that each class gets its fully qualified name. Other content is
+  ignored.
 */
 
 type HeaderFile struct {
@@ -50,13 +53,101 @@ func newHeaderFile(fn estring) *HeaderFile {
 		return hf
 	}
 
-	hf.contents = estring(c)
+	o := optionsFor(filepath.Dir(string(fn)))
+	hf.contents = newPreprocessor(hf, o.defines).process(estring(c))
+	hf.contents = stripMacros(hf.contents, o.stripMacros)
 	hf.v = true
 	headers = append(headers, hf)
 	hf.parse()
 	return hf
 }
 
+/*! Returns \a contents with each occurrence of the identifiers in \a
+  macros removed, so that e.g. "class MYLIB_EXPORT Foo" reads as
+  "class Foo". Only whole identifiers are removed.
+
+  A macro written with parentheses, e.g. "Q_PROPERTY()", is removed
+  along with the argument list which follows it. Line breaks in the
+  argument list are kept, so that line numbers don't change.
+*/
+
+func stripMacros(contents estring, macros []string) estring {
+	if len(macros) == 0 {
+		return contents
+	}
+	strip := make(string_dict)
+	withArguments := make(string_dict)
+	for _, m := range macros {
+		n := estring(m)
+		if n.endsWith("()") {
+			n = n.mid(0, n.length()-2)
+			withArguments.insert(n)
+		}
+		strip.insert(n)
+	}
+	r := make([]byte, 0, contents.length())
+	i := 0
+	for i < contents.length() {
+		c := contents[i]
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			j := i
+			for j < contents.length() &&
+				(contents[j] == '_' ||
+					(contents[j] >= 'a' && contents[j] <= 'z') ||
+					(contents[j] >= 'A' && contents[j] <= 'Z') ||
+					(contents[j] >= '0' && contents[j] <= '9')) {
+				j++
+			}
+			n := contents.mid(i, j-i)
+			if !strip.contains(n) {
+				r = append(r, contents[i:j]...)
+			}
+			i = j
+			if withArguments.contains(n) {
+				k := i
+				for contents.at(k) == ' ' || contents.at(k) == '\t' ||
+					contents.at(k) == '\n' {
+					k++
+				}
+				if contents.at(k) == '(' {
+					level := 0
+					for k < contents.length() {
+						if contents[k] == '(' {
+							level++
+						} else if contents[k] == ')' {
+							level--
+						}
+						if contents[k] == '\n' {
+							r = append(r, '\n')
+						}
+						k++
+						if level == 0 {
+							break
+						}
+					}
+					i = k
+				}
+			}
+		} else if c >= '0' && c <= '9' {
+			// a number, or the tail of one; copy it so that "1L" isn't
+			// treated as an identifier
+			j := i
+			for j < contents.length() &&
+				((contents[j] >= '0' && contents[j] <= '9') ||
+					(contents[j] >= 'a' && contents[j] <= 'z') ||
+					(contents[j] >= 'A' && contents[j] <= 'Z')) {
+				j++
+			}
+			r = append(r, contents[i:j]...)
+			i = j
+		} else {
+			r = append(r, c)
+			i++
+		}
+	}
+	return estring(r)
+}
+
 /*! Returns a pointer to the HeaderFile whose unqualified file name is \a
   s, or a null pointer if there is no such HeaderFile.
 */
@@ -73,197 +164,538 @@ func findHeaderFile(s estring) *HeaderFile {
 	return nil
 }
 
-/*! Parses this header file and creates Class and Function objects as
-  appropriate.
+/*! Parses this header file and creates Class, Function and Typedef
+  objects as appropriate.
 
   The parsing is minimalistic: All it does is look for a useful
-  subset of class declarations, and process those.
+  subset of class and typedef declarations, and process those.
 */
 
 func (this *HeaderFile) parse() {
 	p := newParser(this.contents)
-	p.scan("\nclass ")
+	ns := newNamespaceTracker(this.contents)
+	kind, tp := this.nextDefinition(p, ns)
 	for !p.atEnd() {
-		className := p.identifier()
-		var superclass estring
+		if kind == "typedef" || kind == "using" {
+			this.alias(p, nil, ns.namespace(), kind, "", tp)
+		} else {
+			this.classDefinition(p, ns.namespace(), kind, tp)
+		}
+		kind, tp = this.nextDefinition(p, ns)
+	}
+}
+
+/*! Parses the definition of the class at \a p, whose name is seen in
+  \a scope (a namespace or an enclosing class) and which was declared
+  using the keyword \a kind with the template parameters \a tp.
+  Classes nested in its body are parsed recursively.
+
+  Returns the position of the class body's opening brace, or -1 if
+  \a p isn't looking at a class definition (e.g. at a forward
+  declaration). The cursor is left somewhere in the body.
+*/
+
+func (this *HeaderFile) classDefinition(p *Parser, scope, kind, tp estring) int {
+	// members and bases of structs and unions are public by default
+	defaultAccess := estring("public")
+	if kind == "class" {
+		defaultAccess = "private"
+	}
+	name := p.identifier()
+	className := qualified(scope, name)
+	body := -1
+	var superclasses, access estringlist
+	p.whitespace()
+	// skip "final" and attributes, as in "class Foo final : public Bar"
+	for !p.atEnd() {
+		start := p.i
+		if p.lookingAt("[[") {
+			p.i = p.balancedAt(p.i)
+		} else if p.word() != "final" {
+			p.i = start
+		}
+		if p.i == start {
+			break
+		}
 		p.whitespace()
-		if p.lookingAt(":") {
+	}
+	if p.lookingAt(":") {
+		again := true
+		for again && !p.atEnd() {
 			p.step()
-			inheritance := p.word()
-			if inheritance != "public" {
-				docError(this, p.line(), "Non-public inheritance for class "+className)
-				return
+			p.whitespace()
+			inheritance := defaultAccess
+			for {
+				start := p.i
+				w := p.word()
+				if w == "public" || w == "protected" || w == "private" {
+					inheritance = w
+				} else if w != "virtual" {
+					p.i = start
+					break
+				}
+				p.whitespace()
 			}
 			parent := p.identifier()
 			if parent.isEmpty() {
 				docError(this, p.line(),
 					"Cannot parse superclass name for class "+
 						className)
-				return
+				break
 			}
-			superclass = parent
-
-			{
-				// rudimentary handling of multiple inheritance (by ignoring it)
-				p.whitespace()
-
-				if p.lookingAt(",") {
-					docError(this, p.line(), "Skipping multiple inheritance on class "+className)
-					for !p.lookingAt("{") {
-						p.step()
-						p.whitespace()
-					}
-				}
+			superclasses = append(superclasses, parent)
+			access = append(access, inheritance)
+			p.whitespace()
+			again = p.lookingAt(",")
+		}
+	}
+	p.whitespace()
+	if p.lookingAt("{") && !name.isEmpty() {
+		body = p.i
+		c := findClass(className)
+		if c == nil {
+			c = newClass(className, nil, 0)
+		}
+		if len(c.bases()) == 0 {
+			for i, superclass := range superclasses {
+				c.addBase(superclass, access[i])
 			}
 		}
-		p.whitespace()
-		if p.lookingAt("{") {
-			c := findClass(className)
-			if c == nil {
-				c = newClass(className, nil, 0)
+		if c.file() != nil {
+			docError(this, p.line(),
+				"Class "+className+
+					" conflicts with "+className+" at "+
+					c.file().Name()+":"+
+					fn(c.line(), 10))
+			docError(c.file(), c.line(),
+				"Class "+className+
+					" conflicts with "+className+" at "+
+					this.Name()+":"+
+					fn(p.line(), 10))
+		} else {
+			if false {
+				log.Printf("Found class definition")
 			}
-			c.setParent(superclass)
-			if c != nil && c.file() != nil {
-				docError(this, p.line(),
-					"Class "+className+
-						" conflicts with "+className+" at "+
-						c.file().Name()+":"+
-						fn(c.line(), 10))
-				docError(c.file(), c.line(),
-					"Class "+className+
-						" conflicts with "+className+" at "+
-						this.Name()+":"+
-						fn(p.line(), 10))
-			} else {
-				if false {
-					log.Printf("Found class definition")
-				}
-				c.setSource(this, p.line())
+			c.setSource(this, p.line())
+			c.setKind(kind)
+			c.setTemplateParameters(tp)
+		}
+		p.step()
+		ok := false
+		access := defaultAccess
+		for {
+			ok = false
+			p.whitespace()
+			for p.lookingAt("public:") ||
+				p.lookingAt("private:") ||
+				p.lookingAt("protected:") {
+				access = p.word()
+				p.scan(":")
+				p.step()
+				p.whitespace()
 			}
-			p.step()
-			ok := false
-			for {
-				ok = false
+			ftp := p.templateParameters()
+			if p.lookingAt("class ") || p.lookingAt("struct ") ||
+				p.lookingAt("union ") {
+				nk := p.word()
 				p.whitespace()
-				for p.lookingAt("public:") ||
-					p.lookingAt("private:") ||
-					p.lookingAt("protected:") {
-					p.scan(":")
+				start := p.i
+				if nb := this.classDefinition(p, className, nk, ftp); nb >= 0 {
+					p.i = nb
+					p.block()
+					p.scan(";")
+					continue
+				} else if p.lookingAt(";") {
+					// a forward declaration
 					p.step()
-					p.whitespace()
+					continue
+				} else if p.lookingAt("{") {
+					// an anonymous struct or union
+					p.block()
+					p.scan(";")
+					continue
 				}
-				if p.lookingAt("virtual ") {
+				// a member variable, e.g. "struct stat s;"
+				p.i = start
+			}
+			if p.lookingAt("typedef ") || p.lookingAt("using ") {
+				this.alias(p, c, className, p.word(), access, ftp)
+				continue
+			}
+			specifiers := p.leadingSpecifiers()
+			p.whitespace()
+			var t estring
+			var n estring
+			l := p.line()
+			if p.lookingAt("operator ") {
+				n = p.identifier()
+			} else if p.lookingAt("enum ") {
+				p.scan(" ")
+				p.whitespace()
+				if p.lookingAt("class ") || p.lookingAt("struct ") {
 					p.scan(" ")
 				}
+				n = p.word()
 				p.whitespace()
-				var t estring
-				var n estring
-				l := p.line()
-				if p.lookingAt("operator ") {
-					n = p.identifier()
-				} else if p.lookingAt("enum ") {
-					p.scan(" ")
-					//e := newEnum(c, p.word(), this, l)
+				if p.lookingAt(":") {
+					// the underlying type
+					p.step()
+					p.parseType()
 					p.whitespace()
-					if p.lookingAt("{") {
-						again := true
-						for again {
+				}
+				e := c.enumNamed(n)
+				if e == nil {
+					e = newEnum(c, n, this, l)
+				}
+				e.setAccess(access)
+				if p.lookingAt("{") {
+					again := true
+					for again {
+						p.step()
+						p.whitespace()
+						if p.lookingAt("}") {
+							// trailing comma
+							break
+						}
+						v := p.word()
+						p.whitespace()
+						var init estring
+						if p.lookingAt("=") {
 							p.step()
+							init = p.initializer()
 							p.whitespace()
-							v := p.word()
-							if v.isEmpty() {
-								docError(this, p.line(),
-									"Could not parse enum value")
-							} else {
-								//e.addValue(v)
-							}
-							p.whitespace()
-							if p.lookingAt("=") {
-								p.step()
-								p.whitespace()
-								p.value()
-								p.whitespace()
-							}
-							again = p.lookingAt(",")
 						}
-						if p.lookingAt("}") {
-							p.step()
-							ok = true
-						} else {
+						if v.isEmpty() {
 							docError(this, p.line(),
-								"Enum definition for "+
-									className+"::"+n+
-									" does not end with '}'")
+								"Could not parse enum value")
+						} else if !e.hasValue(v) {
+							e.addValue(v, init)
 						}
-					} else if p.lookingAt(";") {
-						// senseless crap
+						again = p.lookingAt(",")
+					}
+					if p.lookingAt("}") {
+						p.step()
 						ok = true
 					} else {
-						docError(this, l,
-							"Cannot parse enum "+
-								className+"::"+n)
+						docError(this, p.line(),
+							"Enum definition for "+
+								className+"::"+n+
+								" does not end with '}'")
 					}
-				} else if p.lookingAt("typedef ") {
+				} else if p.lookingAt(";") {
+					// senseless crap
 					ok = true
 				} else {
-					t = p.parseType()
-					n = p.identifier()
-					if n.isEmpty() {
-						// constructor/destructor?
-						if t == className || t == "~"+className {
-							n = t
-							t = ""
-						} else if t.isEmpty() && p.lookingAt("~") {
-							p.step()
-							n = "~" + p.identifier()
+					docError(this, l,
+						"Cannot parse enum "+
+							className+"::"+n)
+				}
+				n = ""
+			} else {
+				t = p.parseType()
+				n = p.identifier()
+				if n.isEmpty() {
+					// constructor/destructor?
+					base := withoutTemplateArguments(unqualified(className))
+					if t == base || t == "~"+base {
+						n = t
+						t = ""
+					} else if t.isEmpty() && p.lookingAt("~") {
+						p.step()
+						n = "~" + p.identifier()
+					} else if t.find(" ") > 0 && this.atDeclarator(p) {
+						// "unsigned flags;" parses as a type
+						i := t.length()
+						for t[i-1] != ' ' {
+							i--
 						}
+						n = t.mid(i, t.length()-i)
+						t = t.mid(0, i-1)
 					}
 				}
-				if !n.isEmpty() {
-					p.whitespace()
-					if p.lookingAt(";") {
-						ok = true
-					}
-					a := p.argumentList()
-					p.whitespace()
-					fc := false
-					if p.lookingAt("const") {
-						fc = true
-						p.word()
+			}
+			if !n.isEmpty() && !t.isEmpty() && this.atDeclarator(p) {
+				ok = this.memberVariables(p, c, t, n, l,
+					access, specifiers)
+				n = ""
+			}
+			if !n.isEmpty() {
+				p.whitespace()
+				if p.lookingAt(";") {
+					ok = true
+				}
+				a := p.argumentList()
+				p.whitespace()
+				fc := false
+				if p.lookingAt("const") {
+					fc = true
+					p.word()
+				}
+				specifiers = append(specifiers, p.trailingSpecifiers()...)
+				if !n.isEmpty() && n.find(":") < 0 &&
+					!a.isEmpty() {
+					n = className + "::" + n
+					f := findFunction(n, a, fc)
+					if f == nil {
+						f = newFunction(t, n, a, fc, this, l)
 					}
-					if !n.isEmpty() && n.find(":") < 0 &&
-						!a.isEmpty() {
-						n = className + "::" + n
-						f := findFunction(n, a, fc)
-						if f == nil {
-							f = newFunction(t, n, a, fc, this, l)
-						}
-						ok = true
+					f.setDeclaration(access, specifiers)
+					if !ftp.isEmpty() {
+						f.setTemplateParameters(ftp)
 					}
+					ok = true
 				}
-				if ok {
-					p.whitespace()
-					if p.lookingAt("{") {
-						level := 0
-						for level > 0 || p.lookingAt("{") {
-							if p.lookingAt("{") {
-								level++
-							} else if p.lookingAt("}") {
-								level--
-							}
-							p.step()
-							p.whitespace()
-						}
-					} else {
-						p.scan(";")
-					}
+			}
+			if ok {
+				p.whitespace()
+				if p.lookingAt("{") {
+					p.block()
+				} else {
+					p.scan(";")
 				}
+			}
 
-				if !ok {
-					break
-				}
+			if !ok {
+				break
 			}
 		}
-		p.scan("\nclass ")
 	}
+	return body
+}
+
+/*! Returns true if \a p, which has just parsed the type and name of
+  a member, is looking at something which may follow the name of a
+  member variable, e.g. '=' or ';', rather than at an argument list.
+*/
+
+func (this *HeaderFile) atDeclarator(p *Parser) bool {
+	p.whitespace()
+	return p.lookingAt(";") || p.lookingAt("=") || p.lookingAt("[") ||
+		p.lookingAt("{") || p.lookingAt(",") ||
+		(p.lookingAt(":") && !p.lookingAt("::"))
+}
+
+/*! Parses the rest of a member variable declaration in class \a c,
+  whose type is \a t and whose first name \a n has just been
+  parsed at line \a l, and creates a Variable for each name
+  declared. \a access and \a specifiers apply to all of them.
+
+  Returns true if the declaration ends with ';', leaving \a p there,
+  and false if it cannot be parsed.
+*/
+
+func (this *HeaderFile) memberVariables(p *Parser, c *Class, t, n estring, l int, access estring, specifiers estringlist) bool {
+	base := t
+	for base.endsWith("*") || base.endsWith("&") {
+		base.truncate(base.length() - 1)
+	}
+	for {
+		vt := t
+		p.whitespace()
+		for p.lookingAt("[") {
+			p.step()
+			vt += "[" + p.textUntil("]").simplified() + "]"
+			p.whitespace()
+		}
+		if p.lookingAt(":") {
+			// a bit-field's width
+			p.step()
+			p.value()
+			p.whitespace()
+		}
+		var init estring
+		if p.lookingAt("=") {
+			p.step()
+			init = p.initializer()
+		} else if p.lookingAt("{") {
+			init = p.initializer()
+		}
+		v := c.variableNamed(n)
+		if v == nil {
+			v = newVariable(c, vt, n, init, this, l)
+		}
+		v.setDeclaration(access, specifiers)
+		p.whitespace()
+		if !p.lookingAt(",") {
+			return p.lookingAt(";")
+		}
+		p.step()
+		p.whitespace()
+		t = base
+		for p.lookingAt("*") || p.lookingAt("&") {
+			t += p.t.mid(p.i, 1)
+			p.step()
+			p.whitespace()
+		}
+		l = p.line()
+		n = p.identifier()
+		if n.isEmpty() {
+			docError(this, l, "Cannot parse member variable in class "+
+				c.name())
+			return false
+		}
+	}
+}
+
+/*! Moves \a p past the keyword of the next class, struct or union
+  definition or typedef or alias declaration at namespace level, or
+  to the end of the file, and advances \a ns to match. A definition
+  must start its line, but may be indented and preceded by a template
+  header.
+
+  Returns the keyword ("class", "struct", "union", "typedef" or
+  "using") and the template parameter list, e.g. "<typename T>", or
+  an empty string if it is not a template.
+*/
+
+func (this *HeaderFile) nextDefinition(p *Parser, ns *NamespaceTracker) (estring, estring) {
+	for {
+		start := -1
+		var kind estring
+		for _, k := range []estring{"class", "struct", "union", "typedef", "using"} {
+			i := -1
+			if !p.atEnd() {
+				i = p.find(k+" ", p.i)
+			}
+			if i >= 0 && (start < 0 || i < start) {
+				start = i
+				kind = k
+			}
+		}
+		if start < 0 {
+			p.i = this.contents.length()
+			return "", ""
+		}
+		p.i = start + kind.length() + 1
+		i, tp := this.templateHeaderBefore(start)
+		for i > 0 && (this.contents[i-1] == ' ' || this.contents[i-1] == '\t') {
+			i--
+		}
+		if i == 0 || this.contents[i-1] == '\n' {
+			ns.advance(start)
+			if !ns.nested() {
+				return kind, tp
+			}
+		}
+	}
+}
+
+/*! Parses the typedef or alias declaration at \a p, which follows
+  \a keyword ("typedef" or "using"), and creates a Typedef for it in
+  \a scope. \a c is the class whose body \a p is in, or a null
+  pointer at namespace level, in which case \a access is ignored.
+  \a tp is the template parameter list of an alias template.
+
+  Using-declarations and using-directives are skipped. In any case
+  the cursor is left after the declaration's semicolon.
+*/
+
+func (this *HeaderFile) alias(p *Parser, c *Class, scope, keyword, access, tp estring) {
+	p.whitespace()
+	l := p.line()
+	var name, t estring
+	ni := -1
+	if keyword == "using" {
+		start := p.i
+		name = p.identifier()
+		p.whitespace()
+		if name.isEmpty() || name.contains("::") || !p.lookingAt("=") {
+			// "using namespace std;" or "using Base::f;"
+			p.i = start
+			p.scan(";")
+			return
+		}
+		p.step()
+		t = p.textUntil(";").simplified()
+	} else {
+		var decl estring
+		if p.lookingAt("struct ") || p.lookingAt("class ") ||
+			p.lookingAt("union ") || p.lookingAt("enum ") {
+			// "typedef struct Foo { ... } Bar;" defines Foo as well
+			kind := p.word()
+			p.whitespace()
+			start := p.i
+			body := -1
+			if kind != "enum" {
+				body = this.classDefinition(p, scope, kind, "")
+			}
+			p.i = start
+			decl = kind + " " + p.identifier()
+			if decl == kind {
+				decl += " {...}"
+			}
+			p.whitespace()
+			if body >= 0 {
+				p.i = body
+			}
+			if p.lookingAt("{") {
+				p.block()
+			}
+		}
+		decl = (decl + " " + p.textUntil(";")).simplified()
+		s, n := declaratorName(decl)
+		if s < 0 || hasSeveralDeclarators(decl) {
+			docError(this, l, "Cannot parse typedef "+decl)
+			return
+		}
+		name = decl.mid(s, n)
+		pre := decl.mid(0, s).simplified()
+		post := decl.mid(s+n, decl.length()-s-n).simplified()
+		t = pre + post
+		ni = pre.length()
+	}
+	td := findTypedef(qualified(scope, name))
+	if td == nil {
+		td = newTypedef(c, qualified(scope, name), t, ni, this, l)
+	}
+	if c != nil {
+		td.setAccess(access)
+	}
+	if !tp.isEmpty() {
+		td.setTemplateParameters(tp)
+	}
+}
+
+/*! Looks for a template header such as "template<class T>" just
+  before position \a i, possibly on an earlier line. Returns the
+  position where the header starts and its parameter list, or \a i
+  and an empty string if there is no such header.
+*/
+
+func (this *HeaderFile) templateHeaderBefore(i int) (int, estring) {
+	t := this.contents
+	j := i
+	for j > 0 && (t[j-1] == ' ' || t[j-1] == '\t' || t[j-1] == '\n' ||
+		t[j-1] == '\r') {
+		j--
+	}
+	if j == 0 || t[j-1] != '>' {
+		return i, ""
+	}
+	end := j
+	level := 0
+	for j > 0 {
+		j--
+		if t[j] == '>' {
+			level++
+		} else if t[j] == '<' {
+			level--
+			if level == 0 {
+				break
+			}
+		} else if t[j] == ';' || t[j] == '{' || t[j] == '}' {
+			return i, ""
+		}
+	}
+	if level != 0 {
+		return i, ""
+	}
+	k := j
+	for k > 0 && (t[k-1] == ' ' || t[k-1] == '\t') {
+		k--
+	}
+	if k < 8 || t.mid(k-8, 8) != "template" {
+		return i, ""
+	}
+	return k - 8, t.mid(j, end-j).simplified()
 }
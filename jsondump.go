@@ -0,0 +1,528 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+/*! \class JsonDump jsondump.h
+  The JsonDump class writes udoc's documentation model as JSON.
+
+  It implements OutputBackend so that it can record the paragraphs
+  each DocBlock renders to. When output is finished, it writes
+  udoc-model.json, which contains every Intro, Namespace, Class,
+  Function, Variable, Typedef and DocBlock. Each rendered paragraph
+  is plain text; links are represented by their text.
+
+  The top-level object's "schema" is always "udoc-model" and its
+  "version" is jsonSchemaVersion. The version is incremented whenever
+  a field is removed or changes meaning; new fields may be added
+  without changing it.
+*/
+
+const jsonSchemaVersion = 1
+
+type jsonModel struct {
+	Schema     string          `json:"schema"`
+	Version    int             `json:"version"`
+	Intros     []jsonIntro     `json:"intros"`
+	Namespaces []jsonNamespace `json:"namespaces"`
+	Classes    []jsonClass     `json:"classes"`
+	Functions  []jsonFunction  `json:"functions"`
+	Typedefs   []jsonTypedef   `json:"typedefs"`
+	DocBlocks  []jsonDocBlock  `json:"docBlocks"`
+}
+
+type jsonLocation struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+type jsonIntro struct {
+	Name    string   `json:"name"`
+	Classes []string `json:"classes"`
+}
+
+type jsonNamespace struct {
+	Name       string   `json:"name"`
+	Namespaces []string `json:"namespaces"`
+	Classes    []string `json:"classes"`
+}
+
+type jsonClass struct {
+	Name               string         `json:"name"`
+	Kind               string         `json:"kind"`
+	Namespace          *string        `json:"namespace"`
+	TemplateParameters string         `json:"templateParameters"`
+	Specializes        *string        `json:"specializes"`
+	Parent             *string        `json:"parent"`
+	Bases              []jsonBase     `json:"bases"`
+	Subclasses         []string       `json:"subclasses"`
+	Members            []string       `json:"members"`
+	Enums              []jsonEnum     `json:"enums"`
+	Variables          []jsonVariable `json:"variables"`
+	Location           *jsonLocation  `json:"location"`
+	Documented         bool           `json:"documented"`
+}
+
+type jsonEnum struct {
+	Name       string          `json:"name"`
+	Values     []jsonEnumValue `json:"values"`
+	Access     string          `json:"access"`
+	Location   *jsonLocation   `json:"location"`
+	Documented bool            `json:"documented"`
+}
+
+type jsonEnumValue struct {
+	Name        string `json:"name"`
+	Initializer string `json:"initializer"`
+	Documented  bool   `json:"documented"`
+}
+
+type jsonVariable struct {
+	Name        string        `json:"name"`
+	Type        string        `json:"type"`
+	Initializer string        `json:"initializer"`
+	Access      string        `json:"access"`
+	Specifiers  []string      `json:"specifiers"`
+	Location    *jsonLocation `json:"location"`
+	Documented  bool          `json:"documented"`
+}
+
+type jsonBase struct {
+	Name   string `json:"name"`
+	Access string `json:"access"`
+}
+
+type jsonFunction struct {
+	Signature          string        `json:"signature"`
+	Class              string        `json:"class"`
+	Namespace          *string       `json:"namespace"`
+	Relates            *string       `json:"relates"`
+	TemplateParameters string        `json:"templateParameters"`
+	Name               string        `json:"name"`
+	ReturnType         string        `json:"returnType"`
+	Arguments          string        `json:"arguments"`
+	Types              string        `json:"argumentTypes"`
+	Const              bool          `json:"const"`
+	Access             string        `json:"access"`
+	Specifiers         []string      `json:"specifiers"`
+	Overload           bool          `json:"overload"`
+	Reimplements       *string       `json:"reimplements"`
+	Location           *jsonLocation `json:"location"`
+	Documented         bool          `json:"documented"`
+}
+
+type jsonTypedef struct {
+	Name               string        `json:"name"`
+	Class              string        `json:"class"`
+	Namespace          *string       `json:"namespace"`
+	Type               string        `json:"type"`
+	Alias              bool          `json:"alias"`
+	TemplateParameters string        `json:"templateParameters"`
+	Access             string        `json:"access"`
+	Location           *jsonLocation `json:"location"`
+	Documented         bool          `json:"documented"`
+}
+
+type jsonSubject struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+type jsonDocBlock struct {
+	Location   *jsonLocation `json:"location"`
+	Documents  jsonSubject   `json:"documents"`
+	Text       string        `json:"text"`
+	Paragraphs []string      `json:"paragraphs"`
+}
+
+type jsonDumpT struct {
+	directory  estring
+	current    *DocBlock
+	headline   bool
+	para       estring
+	paragraphs map[*DocBlock][]string
+}
+
+/*! Constructs a JsonDump which will write to directory \a dir. */
+
+func newJsonDump(dir estring) *jsonDumpT {
+	return &jsonDumpT{
+		directory:  dir,
+		paragraphs: make(map[*DocBlock][]string),
+	}
+}
+
+/*! Returns the location of \a line in \a f, or a null pointer if
+  \a f is not known. */
+
+func newJsonLocation(f File, line int) *jsonLocation {
+	if f == nil {
+		return nil
+	}
+	return &jsonLocation{string(f.Name()), line}
+}
+
+/*! Returns the signature which identifies \a f in the JSON model:
+  its full name, argument types and constness. */
+
+func jsonSignature(f *Function) string {
+	s := f.name() + f.a
+	if f.isConst() {
+		s += " const"
+	}
+	return string(s)
+}
+
+func (this *jsonDumpT) startHeadlineIntro(i *Intro) {
+	this.startDocBlock(i.docBlock)
+}
+
+func (this *jsonDumpT) startHeadlineClass(c *Class) {
+	this.startDocBlock(c.db)
+}
+
+/*! Namespace pages are generated entirely from the model, so
+  nothing on them is recorded. */
+
+func (this *jsonDumpT) startHeadlineNamespace(n *Namespace) {
+	this.startDocBlock(nil)
+}
+
+/*! As for namespaces, nothing on a file page is recorded. */
+
+func (this *jsonDumpT) startHeadlineFile(p *FilePage) {
+	this.startDocBlock(nil)
+}
+
+func (this *jsonDumpT) startHeadlineFunction(f *Function) {
+	this.startDocBlock(f.docBlock())
+}
+
+func (this *jsonDumpT) startHeadlineEnum(e *Enum) {
+	this.startDocBlock(e.docBlock())
+}
+
+func (this *jsonDumpT) startHeadlineTypedef(t *Typedef) {
+	this.startDocBlock(t.docBlock())
+}
+
+func (this *jsonDumpT) startHeadlineVariable(v *Variable) {
+	this.startDocBlock(v.docBlock())
+}
+
+/*! Notes that subsequent paragraphs are rendered by \a d. The
+  headline itself is not recorded, since it merely repeats the
+  model. */
+
+func (this *jsonDumpT) startDocBlock(d *DocBlock) {
+	this.endParagraph()
+	this.current = d
+	this.headline = true
+}
+
+/*! Records the current paragraph, if there is one. */
+
+func (this *jsonDumpT) endParagraph() {
+	p := this.para.simplified()
+	this.para = ""
+	if this.headline {
+		this.headline = false
+		return
+	}
+	if this.current == nil || p.isEmpty() {
+		return
+	}
+	this.paragraphs[this.current] = append(this.paragraphs[this.current], string(p))
+}
+
+func (this *jsonDumpT) addText(text estring) {
+	this.para += text
+}
+
+func (this *jsonDumpT) addLink(url, title estring) {
+	this.para += title
+}
+
+func (this *jsonDumpT) addArgument(text estring) {
+	this.para += text
+}
+
+func (this *jsonDumpT) addFunction(text estring, f *Function) {
+	this.para += text
+}
+
+func (this *jsonDumpT) addClass(text estring, c *Class) {
+	this.para += text
+}
+
+func (this *jsonDumpT) addNamespace(text estring, n *Namespace) {
+	this.para += text
+}
+
+func (this *jsonDumpT) addEnum(text estring, e *Enum) {
+	this.para += text
+}
+
+func (this *jsonDumpT) addEnumValue(text estring, e *Enum) {
+	this.para += text
+}
+
+func (this *jsonDumpT) addVariable(text estring, v *Variable) {
+	this.para += text
+}
+
+func (this *jsonDumpT) addTypedef(text estring, t *Typedef) {
+	this.para += text
+}
+
+func (this *jsonDumpT) addCodeBlock(text estring) {
+	this.endParagraph()
+	if this.current != nil {
+		code := strings.Trim(string(text), "\n")
+		this.paragraphs[this.current] = append(this.paragraphs[this.current], code)
+	}
+}
+
+func (this *jsonDumpT) addWarning(text estring) {
+	this.endParagraph()
+	this.para = "Warning: " + text
+	this.endParagraph()
+}
+
+func (this *jsonDumpT) seeAlso(text estring) {
+	this.endParagraph()
+	this.para = "See also: " + text
+	this.endParagraph()
+}
+
+func (this *jsonDumpT) addNote(text estring) {
+	this.endParagraph()
+	this.para = "Note: " + text
+	this.endParagraph()
+}
+
+func (this *jsonDumpT) addSection(prio int, text estring) {
+	this.endParagraph()
+	this.para = text
+	this.endParagraph()
+}
+
+/*! Builds the model and writes it to udoc-model.json. Since this
+  backend has only one file, this is done only once, when Output is
+  finished.
+*/
+
+func (this *jsonDumpT) endPage() {
+	this.endParagraph()
+	m := jsonModel{
+		Schema:     "udoc-model",
+		Version:    jsonSchemaVersion,
+		Intros:     []jsonIntro{},
+		Namespaces: []jsonNamespace{},
+		Classes:    []jsonClass{},
+		Functions:  []jsonFunction{},
+		Typedefs:   []jsonTypedef{},
+		DocBlocks:  []jsonDocBlock{},
+	}
+	for _, i := range intros {
+		ji := jsonIntro{Name: string(i.name()), Classes: []string{}}
+		for _, c := range i.classes {
+			ji.Classes = append(ji.Classes, string(c.name()))
+		}
+		m.Intros = append(m.Intros, ji)
+	}
+	for _, n := range namespaces {
+		jn := jsonNamespace{
+			Name:       string(n.name()),
+			Namespaces: []string{},
+			Classes:    []string{},
+		}
+		for _, o := range namespaces {
+			if o.parent() == n {
+				jn.Namespaces = append(jn.Namespaces, string(o.name()))
+			}
+		}
+		for _, c := range classes {
+			if c.namespace() == n {
+				jn.Classes = append(jn.Classes, string(c.name()))
+			}
+		}
+		m.Namespaces = append(m.Namespaces, jn)
+	}
+	for _, c := range classes {
+		jc := jsonClass{
+			Name:               string(c.name()),
+			Kind:               string(c.kind()),
+			TemplateParameters: string(c.templateParameters()),
+			Bases:              []jsonBase{},
+			Subclasses:         []string{},
+			Members:            []string{},
+			Enums:              []jsonEnum{},
+			Variables:          []jsonVariable{},
+			Location:           newJsonLocation(c.file(), c.line()),
+			Documented:         c.db != nil,
+		}
+		if c.specializes() != nil {
+			s := string(c.specializes().name())
+			jc.Specializes = &s
+		}
+		if c.parent() != nil {
+			p := string(c.parent().name())
+			jc.Parent = &p
+		}
+		for _, b := range c.bases() {
+			n := b.n
+			if b.c != nil {
+				n = b.c.name()
+			}
+			jc.Bases = append(jc.Bases, jsonBase{string(n), string(b.access)})
+		}
+		if c.namespace() != nil {
+			n := string(c.namespace().name())
+			jc.Namespace = &n
+		}
+		for _, sub := range c.subclasses() {
+			jc.Subclasses = append(jc.Subclasses, string(sub.name()))
+		}
+		for _, f := range c.members() {
+			jc.Members = append(jc.Members, jsonSignature(f))
+		}
+		for _, e := range c.enums() {
+			je := jsonEnum{
+				Name:       string(e.name()),
+				Values:     []jsonEnumValue{},
+				Access:     string(e.access()),
+				Location:   newJsonLocation(e.file(), e.line()),
+				Documented: e.docBlock() != nil,
+			}
+			for _, v := range e.enumValues() {
+				je.Values = append(je.Values,
+					jsonEnumValue{string(v.n), string(v.init), v.documented})
+			}
+			jc.Enums = append(jc.Enums, je)
+		}
+		for _, v := range c.variables() {
+			jv := jsonVariable{
+				Name:        string(v.name()),
+				Type:        string(v.typeStr()),
+				Initializer: string(v.initializer()),
+				Access:      string(v.access()),
+				Specifiers:  []string{},
+				Location:    newJsonLocation(v.file(), v.line()),
+				Documented:  v.docBlock() != nil,
+			}
+			for _, s := range v.specifiers() {
+				jv.Specifiers = append(jv.Specifiers, string(s))
+			}
+			jc.Variables = append(jc.Variables, jv)
+		}
+		m.Classes = append(m.Classes, jc)
+	}
+	for _, f := range functions {
+		var class string
+		if f.parent() != nil {
+			class = string(f.parent().name())
+		}
+		var namespace, relates *string
+		if f.namespace() != nil {
+			n := string(f.namespace().name())
+			namespace = &n
+		}
+		if f.relates() != nil {
+			r := string(f.relates().name())
+			relates = &r
+		}
+		specifiers := []string{}
+		for _, s := range f.specifiers() {
+			specifiers = append(specifiers, string(s))
+		}
+		var reimplements *string
+		if f.super() != nil {
+			s := jsonSignature(f.super())
+			reimplements = &s
+		}
+		m.Functions = append(m.Functions, jsonFunction{
+			Signature:          jsonSignature(f),
+			Class:              class,
+			Namespace:          namespace,
+			Relates:            relates,
+			TemplateParameters: string(f.templateParameters()),
+			Name:               string(f.name()),
+			ReturnType:         string(f.typeStr()),
+			Arguments:          string(f.arguments()),
+			Types:              string(f.a),
+			Const:              f.isConst(),
+			Access:             string(f.access()),
+			Specifiers:         specifiers,
+			Overload:           f.hasOverload(),
+			Reimplements:       reimplements,
+			Location:           newJsonLocation(f.file(), f.line()),
+			Documented:         f.docBlock() != nil,
+		})
+	}
+	for _, td := range typedefs {
+		var class string
+		if td.parent() != nil {
+			class = string(td.parent().name())
+		}
+		var namespace *string
+		if td.namespace() != nil {
+			n := string(td.namespace().name())
+			namespace = &n
+		}
+		m.Typedefs = append(m.Typedefs, jsonTypedef{
+			Name:               string(td.fullName()),
+			Class:              class,
+			Namespace:          namespace,
+			Type:               string(td.typeStr()),
+			Alias:              td.isAlias(),
+			TemplateParameters: string(td.templateParameters()),
+			Access:             string(td.access()),
+			Location:           newJsonLocation(td.file(), td.line()),
+			Documented:         td.docBlock() != nil,
+		})
+	}
+	for _, d := range docBlocks {
+		jd := jsonDocBlock{
+			Location:   newJsonLocation(d.file, d.line),
+			Text:       string(d.t),
+			Paragraphs: this.paragraphs[d],
+		}
+		if jd.Paragraphs == nil {
+			jd.Paragraphs = []string{}
+		}
+		if d.f != nil {
+			jd.Documents = jsonSubject{"function", jsonSignature(d.f)}
+		} else if d.e != nil {
+			jd.Documents = jsonSubject{"enum", string(d.e.fullName())}
+		} else if d.v != nil {
+			jd.Documents = jsonSubject{"variable", string(d.v.fullName())}
+		} else if d.td != nil {
+			jd.Documents = jsonSubject{"typedef", string(d.td.fullName())}
+		} else if d.c != nil {
+			jd.Documents = jsonSubject{"class", string(d.c.name())}
+		} else if d.i != nil {
+			jd.Documents = jsonSubject{"intro", string(d.i.name())}
+		}
+		m.DocBlocks = append(m.DocBlocks, jd)
+	}
+
+	var b bytes.Buffer
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	e.SetIndent("", "  ")
+	err := e.Encode(m)
+	if err != nil {
+		panic(fmt.Sprintf("Can't encode documentation model: %s", err))
+	}
+	filename := this.directory + "/udoc-model.json"
+	err = ioutil.WriteFile(string(filename), b.Bytes(), 0644)
+	if err != nil {
+		panic(fmt.Sprintf("Can't write %s: %s", filename, err))
+	}
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+/*! \class ManPage manpage.h
+  The ManPage class provides documentation output as Unix man pages.
+
+  It implements OutputBackend and writes one section-3 man page in
+  troff format for each Class, with each Function as a subsection.
+  Arguments are italicised, code blocks are left unfilled, and every
+  class mentioned on the page is listed under SEE ALSO.
+
+  Intros have no man page; their output is discarded.
+*/
+
+type manpageT struct {
+	fd        *os.File
+	directory estring
+	para      estring
+	pp        bool
+	bol       bool
+	headline  bool
+	fn        estring
+	related   estringlist
+}
+
+/*! Constructs a man page generator that'll write to files in
+  directory \a dir. */
+
+func newManpage(dir estring) *manpageT {
+	return &manpageT{
+		directory: dir,
+	}
+}
+
+/*! As Output::startHeadline(). Intros have no man page, so this
+  merely ends the current page. */
+
+func (this *manpageT) startHeadlineIntro(i *Intro) {
+	this.endPage()
+}
+
+/*! As Output::startHeadline(). \a c is used to derive a file name
+  and the page's NAME section. The headline itself is not output,
+  since the NAME section says the same.
+*/
+
+func (this *manpageT) startHeadlineClass(c *Class) {
+	this.endPage()
+	this.startPage(c.name())
+	this.output(".TH " + manEscape(c.name()) + " 3 \"\" \"" +
+		manEscape(output.owner()) + "\" \"udoc\"\n")
+	this.output(".SH NAME\n" + manEscape(c.name()) + "\n")
+	this.output(".SH DESCRIPTION\n")
+	this.bol = true
+	this.headline = true
+}
+
+/*! As Output::startHeadline(). \a n is used to derive a file name
+  and the page's NAME section, as for classes.
+*/
+
+func (this *manpageT) startHeadlineNamespace(n *Namespace) {
+	this.endPage()
+	this.startPage(n.name())
+	this.output(".TH " + manEscape(n.name()) + " 3 \"\" \"" +
+		manEscape(output.owner()) + "\" \"udoc\"\n")
+	this.output(".SH NAME\n" + manEscape(n.name()) + "\n")
+	this.output(".SH DESCRIPTION\n")
+	this.bol = true
+	this.headline = true
+}
+
+/*! As Output::startHeadline(). The base name of \a p's file is
+  used to derive a file name and the page's NAME section, as for
+  classes.
+*/
+
+func (this *manpageT) startHeadlineFile(p *FilePage) {
+	name := estring(filepath.Base(string(p.name())))
+	this.endPage()
+	this.startPage(name)
+	this.output(".TH " + manEscape(name) + " 3 \"\" \"" +
+		manEscape(output.owner()) + "\" \"udoc\"\n")
+	this.output(".SH NAME\n" + manEscape(name) + "\n")
+	this.output(".SH DESCRIPTION\n")
+	this.bol = true
+	this.headline = true
+}
+
+/*! As Output::startHeadline(). Each function is a subsection. */
+
+func (this *manpageT) startHeadlineFunction(f *Function) {
+	this.endParagraph()
+	this.output(".SS ")
+	this.bol = true
+	this.para = "\n"
+}
+
+/*! As Output::startHeadline(). Each enum is a subsection. */
+
+func (this *manpageT) startHeadlineEnum(e *Enum) {
+	this.endParagraph()
+	this.output(".SS ")
+	this.bol = true
+	this.para = "\n"
+}
+
+/*! As Output::startHeadline(). Each member variable is a
+  subsection. */
+
+func (this *manpageT) startHeadlineVariable(v *Variable) {
+	this.endParagraph()
+	this.output(".SS ")
+	this.bol = true
+	this.para = "\n"
+}
+
+/*! As Output::startHeadline(). Each typedef is a subsection. */
+
+func (this *manpageT) startHeadlineTypedef(t *Typedef) {
+	this.endParagraph()
+	this.output(".SS ")
+	this.bol = true
+	this.para = "\n"
+}
+
+/*! As Output::endParagraph(). */
+
+func (this *manpageT) endParagraph() {
+	this.headline = false
+	if this.para.isEmpty() {
+		return
+	}
+	if this.pp {
+		// nothing was written, so there's no paragraph to end
+		this.pp = false
+		this.para = ""
+		return
+	}
+	this.output(this.para)
+	this.para = ""
+	this.bol = true
+}
+
+/*! Returns \a text with backslashes and hyphens escaped for troff. */
+
+func manEscape(text estring) estring {
+	s := make([]byte, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			s = append(s, '\\', 'e')
+		case '-':
+			s = append(s, '\\', '-')
+		case '"':
+			s = append(s, '\\', '(', 'd', 'q')
+		default:
+			s = append(s, text[i])
+		}
+	}
+	return estring(s)
+}
+
+/*! As Output::addText(). \a text is escaped as necessary. */
+
+func (this *manpageT) addText(text estring) {
+	if this.headline {
+		return
+	}
+	if this.para.isEmpty() {
+		this.para = "\n"
+		this.pp = true
+		this.bol = true
+	}
+	if this.bol {
+		for text.startsWith(" ") {
+			text = text.mid(1, text.length()-1)
+		}
+		if text.isEmpty() {
+			return
+		}
+	}
+	this.write(manEscape(text))
+}
+
+/*! Writes the already escaped \a s, starting a paragraph if one is
+  pending and protecting a leading '.' or '\''
+  at the start of a line from being read as a troff request. */
+
+func (this *manpageT) write(s estring) {
+	if s.isEmpty() {
+		return
+	}
+	if this.pp {
+		this.output(".PP\n")
+		this.pp = false
+	}
+	if this.bol && (s[0] == '.' || s[0] == '\'') {
+		this.output("\\&")
+	}
+	this.output(s)
+	this.bol = s.endsWith("\n")
+}
+
+/*! Adds a link to \a url with the given \a title. Since man pages
+  have no links, the URL is shown after the title unless they are
+  the same. */
+
+func (this *manpageT) addLink(url, title estring) {
+	this.addText(title)
+	if url != title {
+		this.addText(" <" + url + ">")
+	}
+}
+
+/*! As Output::addArgument(). \a text is output in italics. */
+
+func (this *manpageT) addArgument(text estring) {
+	this.addText("")
+	this.write("\\fI" + manEscape(text) + "\\fR")
+}
+
+/*! As Output::addFunction(). The name of \a f is output in bold. */
+
+func (this *manpageT) addFunction(text estring, f *Function) {
+	ls, ll := functionLinkSpan(text, f)
+	this.addText(text.mid(0, ls))
+	this.write("\\fB" + manEscape(text.mid(ls, ll)) + "\\fR")
+	this.addText(text.mid(ls+ll, len(text)-ls+ll))
+}
+
+/*! As Output::addClass(). The name of \a c is output in bold, and
+  \a c is listed under SEE ALSO.
+*/
+
+func (this *manpageT) addClass(text estring, c *Class) {
+	if c.name() != this.fn && !this.related.contains(c.name()) {
+		this.related = append(this.related, c.name())
+	}
+	if this.headline {
+		return
+	}
+	ls, ll := classLinkSpan(text, c)
+	this.addText(text.mid(0, ls))
+	this.write("\\fB" + manEscape(text.mid(ls, ll)) + "\\fR")
+	this.addText(text.mid(ls+ll, len(text)-ls+ll))
+}
+
+/*! As Output::addEnum(). The name of \a e or its value is output in
+  bold. */
+
+func (this *manpageT) addEnum(text estring, e *Enum) {
+	ls, ll := enumLinkSpan(text, e)
+	this.addText(text.mid(0, ls))
+	this.write("\\fB" + manEscape(text.mid(ls, ll)) + "\\fR")
+	this.addText(text.mid(ls+ll, len(text)-ls+ll))
+}
+
+/*! As Output::addVariable(). \a text is output in bold. */
+
+func (this *manpageT) addVariable(text estring, v *Variable) {
+	this.addText("")
+	this.write("\\fB" + manEscape(text) + "\\fR")
+}
+
+/*! As Output::addTypedef(). The name of \a t is output in bold. */
+
+func (this *manpageT) addTypedef(text estring, t *Typedef) {
+	ls, ll := typedefLinkSpan(text, t)
+	this.addText(text.mid(0, ls))
+	this.write("\\fB" + manEscape(text.mid(ls, ll)) + "\\fR")
+	this.addText(text.mid(ls+ll, len(text)-ls+ll))
+}
+
+/*! As Output::addEnumValue(). \a text is output in bold. */
+
+func (this *manpageT) addEnumValue(text estring, e *Enum) {
+	this.addText("")
+	this.write("\\fB" + manEscape(text) + "\\fR")
+}
+
+/*! As Output::addNamespace(). The name of \a n is output in bold,
+  and \a n is listed under SEE ALSO.
+*/
+
+func (this *manpageT) addNamespace(text estring, n *Namespace) {
+	if n.name() != this.fn && !this.related.contains(n.name()) {
+		this.related = append(this.related, n.name())
+	}
+	this.addText("")
+	this.write("\\fB" + manEscape(text) + "\\fR")
+}
+
+func (this *manpageT) addCodeBlock(text estring) {
+	for text.startsWith("\n") {
+		text = text.mid(1, text.length()-1)
+	}
+	for text.endsWith("\n") {
+		text.truncate(text.length() - 1)
+	}
+	this.output(".PP\n.RS\n.nf\n")
+	this.bol = true
+	for _, line := range text.split('\n') {
+		this.write(manEscape(line) + "\n")
+	}
+	this.output(".fi\n.RE\n")
+	this.bol = true
+}
+
+func (this *manpageT) addWarning(text estring) {
+	this.endParagraph()
+	this.addText("")
+	this.write("\\fBWarning:\\fR ")
+	this.addText(text)
+}
+
+func (this *manpageT) seeAlso(text estring) {
+	this.endParagraph()
+	this.addText("")
+	this.write("\\fBSee also:\\fR ")
+	this.addText(text)
+}
+
+func (this *manpageT) addNote(text estring) {
+	this.endParagraph()
+	this.addText("")
+	this.write("\\fBNote:\\fR ")
+	this.addText(text)
+}
+
+func (this *manpageT) addSection(prio int, text estring) {
+	this.endParagraph()
+	if prio == 1 {
+		this.output(".SH " + manEscape(text.upper()) + "\n")
+	} else if prio == 2 || prio == 3 {
+		this.output(".SS " + manEscape(text) + "\n")
+	} else {
+		panic(fmt.Sprintf("No support for prio level %d", prio))
+	}
+}
+
+/*! Write \a s to the output file. */
+
+func (this *manpageT) output(s estring) {
+	if this.fd == nil || s.isEmpty() {
+		return
+	}
+
+	this.fd.Write([]byte(s))
+}
+
+/*! Emits the SEE ALSO and COPYRIGHT sections, which end each man
+  page. */
+
+func (this *manpageT) endPage() {
+	if this.fd == nil {
+		return
+	}
+
+	this.endParagraph()
+
+	if len(this.related) > 0 {
+		this.output(".SH SEE ALSO\n")
+		for idx, n := range this.related {
+			s := estring(".BR " + manEscape(n) + " (3)")
+			if idx < len(this.related)-1 {
+				s += ","
+			}
+			this.output(s + "\n")
+		}
+	}
+
+	this.output(".SH COPYRIGHT\nThis man page is based on source code belonging to ")
+	this.output(manEscape(output.owner()))
+	if !output.ownerHome().isEmpty() {
+		this.output(" <" + manEscape(output.ownerHome()) + ">")
+	}
+	this.output(". All rights reserved.\n")
+	this.fd.Close()
+	this.fd = nil
+}
+
+/*! Starts a new man page for the class named \a name. */
+
+func (this *manpageT) startPage(name estring) {
+	this.related.clear()
+	this.fn = name
+	filename := this.directory + "/" + name + ".3"
+	var err error
+	this.fd, err = os.Create(string(filename))
+	if err != nil {
+		panic(fmt.Sprintf("Can't write %s: %s", filename, err))
+	}
+	this.para = ""
+	this.bol = true
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+/*! \class Markdown markdown.h
+  The Markdown class provides documentation output as GitHub-flavoured
+  Markdown.
+
+  It implements OutputBackend and writes one .md file for each Intro
+  and Class, with relative links between them. Each Function gets a
+  third-level heading preceded by an anchor, so links to it work on
+  GitHub and in most wikis.
+*/
+
+type markdownT struct {
+	fd        *os.File
+	directory estring
+	pstart    bool
+	para      estring
+	names     estringlist
+	fn        estring
+}
+
+/*! Constructs a Markdown generator that'll write to files in
+  directory \a dir. */
+
+func newMarkdown(dir estring) *markdownT {
+	return &markdownT{
+		directory: dir,
+	}
+}
+
+/*! As Output::startHeadline(). \a i is used to derive a file name. */
+
+func (this *markdownT) startHeadlineIntro(i *Intro) {
+	this.endPage()
+	this.startPage(i.name().lower())
+	this.output("# ")
+	this.output(markdownEscape(i.name()))
+	this.output("\n\n")
+}
+
+/*! As Output::startHeadline(). \a c is used to derive a file name. */
+
+func (this *markdownT) startHeadlineClass(c *Class) {
+	this.endPage()
+	this.startPage(c.pageName())
+	this.output("# ")
+	this.para = "\n\n"
+	this.pstart = true
+}
+
+/*! As Output::startHeadline(). \a n is used to derive a file name. */
+
+func (this *markdownT) startHeadlineNamespace(n *Namespace) {
+	this.endPage()
+	this.startPage(n.pageName())
+	this.output("# ")
+	this.para = "\n\n"
+	this.pstart = true
+}
+
+/*! As Output::startHeadline(). \a p is used to derive a file name. */
+
+func (this *markdownT) startHeadlineFile(p *FilePage) {
+	this.endPage()
+	this.startPage(p.pageName())
+	this.output("# ")
+	this.para = "\n\n"
+	this.pstart = true
+}
+
+/*! As Output::startHeadline(). \a f is used to create an anchor. */
+
+func (this *markdownT) startHeadlineFunction(f *Function) {
+	a := f.anchor()
+	if !this.names.contains(a) {
+		this.output("<a id=\"" + a + "\"></a>\n\n")
+		this.names = append(this.names, a)
+	}
+	this.output("### ")
+	this.para = "\n\n"
+	this.pstart = true
+}
+
+/*! As Output::startHeadline(). \a e is used to create an anchor. */
+
+func (this *markdownT) startHeadlineEnum(e *Enum) {
+	a := e.anchor()
+	if !this.names.contains(a) {
+		this.output("<a id=\"" + a + "\"></a>\n\n")
+		this.names = append(this.names, a)
+	}
+	this.output("### ")
+	this.para = "\n\n"
+	this.pstart = true
+}
+
+/*! As Output::startHeadline(). \a v is used to create an anchor. */
+
+func (this *markdownT) startHeadlineVariable(v *Variable) {
+	a := v.anchor()
+	if !this.names.contains(a) {
+		this.output("<a id=\"" + a + "\"></a>\n\n")
+		this.names = append(this.names, a)
+	}
+	this.output("### ")
+	this.para = "\n\n"
+	this.pstart = true
+}
+
+/*! As Output::startHeadline(). \a t is used to create an anchor. */
+
+func (this *markdownT) startHeadlineTypedef(t *Typedef) {
+	a := t.anchor()
+	if !this.names.contains(a) {
+		this.output("<a id=\"" + a + "\"></a>\n\n")
+		this.names = append(this.names, a)
+	}
+	this.output("### ")
+	this.para = "\n\n"
+	this.pstart = true
+}
+
+/*! As Output::endParagraph(). */
+
+func (this *markdownT) endParagraph() {
+	if this.para.isEmpty() {
+		return
+	}
+	this.output(this.para)
+	this.para = ""
+}
+
+/*! Returns \a text with all characters that have a special meaning
+  in Markdown escaped by a backslash. */
+
+func markdownEscape(text estring) estring {
+	s := make([]byte, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\', '`', '*', '_', '[', ']', '<', '>', '#', '|':
+			s = append(s, '\\')
+		}
+		s = append(s, text[i])
+	}
+	return estring(s)
+}
+
+/*! As Output::addText(). \a text is escaped as necessary. */
+
+func (this *markdownT) addText(text estring) {
+	if this.para.isEmpty() {
+		this.para = "\n\n"
+		this.pstart = true
+	}
+
+	i := 0
+	if this.pstart {
+		for text.at(i) == ' ' {
+			i++
+		}
+		if i >= text.length() {
+			return
+		}
+		this.pstart = false
+	}
+
+	this.output(markdownEscape(text[i:]))
+}
+
+/*! Adds a link to \a url with the given \a title. */
+
+func (this *markdownT) addLink(url, title estring) {
+	this.addText("")
+	this.pstart = false
+	this.output("[" + markdownEscape(title) + "](" + url + ")")
+}
+
+/*! As Output::addArgument(). \a text is output in italics. */
+
+func (this *markdownT) addArgument(text estring) {
+	this.addText("")
+	this.pstart = false
+	this.output("*" + markdownEscape(text) + "*")
+}
+
+/*! As Output::addFunction(). Only the part of \a text which
+  corresponds to the name of \a f is made into a link.
+*/
+
+func (this *markdownT) addFunction(text estring, f *Function) {
+	ls, ll := functionLinkSpan(text, f)
+	this.addText(text.mid(0, ls))
+	this.pstart = false
+	target := f.pageName()
+	if this.fn == target {
+		target = ""
+	} else {
+		target += ".md"
+	}
+	this.output("[" + markdownEscape(text.mid(ls, ll)) + "](" +
+		target + "#" + f.anchor() + ")")
+	this.addText(text.mid(ls+ll, len(text)-ls+ll))
+}
+
+/*! As Output::addClass(). Only the part of \a text which corresponds
+  to the name of \a c is made into a link, and only if \a c is
+  documented on another page.
+*/
+
+func (this *markdownT) addClass(text estring, c *Class) {
+	ls, ll := classLinkSpan(text, c)
+	this.addText(text.mid(0, ls))
+	this.pstart = false
+	target := c.pageName()
+	if target == this.fn {
+		this.addText(text.mid(ls, ll))
+	} else {
+		this.output("[" + markdownEscape(text.mid(ls, ll)) + "](" +
+			target + ".md)")
+	}
+	this.addText(text.mid(ls+ll, len(text)-ls+ll))
+}
+
+/*! As Output::addNamespace(). \a text is linked to the page of \a
+  n, unless that is the current page. */
+
+func (this *markdownT) addNamespace(text estring, n *Namespace) {
+	this.addText("")
+	this.pstart = false
+	target := n.pageName()
+	if target == this.fn {
+		this.addText(text)
+	} else {
+		this.output("[" + markdownEscape(text) + "](" + target + ".md)")
+	}
+}
+
+/*! As Output::addEnum(). Only the part of \a text which corresponds
+  to the name of \a e or one of its values is made into a link.
+*/
+
+func (this *markdownT) addEnum(text estring, e *Enum) {
+	ls, ll := enumLinkSpan(text, e)
+	this.addText(text.mid(0, ls))
+	this.pstart = false
+	target := e.parent().pageName()
+	if this.fn == target {
+		target = ""
+	} else {
+		target += ".md"
+	}
+	this.output("[" + markdownEscape(text.mid(ls, ll)) + "](" +
+		target + "#" + e.anchor() + ")")
+	this.addText(text.mid(ls+ll, len(text)-ls+ll))
+}
+
+/*! As Output::addVariable(). \a text is made into a link to \a v.
+ */
+
+func (this *markdownT) addVariable(text estring, v *Variable) {
+	this.addText("")
+	this.pstart = false
+	target := v.parent().pageName()
+	if this.fn == target {
+		target = ""
+	} else {
+		target += ".md"
+	}
+	this.output("[" + markdownEscape(text) + "](" +
+		target + "#" + v.anchor() + ")")
+}
+
+/*! As Output::addTypedef(). Only the part of \a text which
+  corresponds to the name of \a t is made into a link.
+*/
+
+func (this *markdownT) addTypedef(text estring, t *Typedef) {
+	ls, ll := typedefLinkSpan(text, t)
+	this.addText(text.mid(0, ls))
+	this.pstart = false
+	target := t.pageName()
+	if this.fn == target {
+		target = ""
+	} else {
+		target += ".md"
+	}
+	this.output("[" + markdownEscape(text.mid(ls, ll)) + "](" +
+		target + "#" + t.anchor() + ")")
+	this.addText(text.mid(ls+ll, len(text)-ls+ll))
+}
+
+/*! As Output::addEnumValue(). \a text is output in bold. */
+
+func (this *markdownT) addEnumValue(text estring, e *Enum) {
+	this.addText("")
+	this.pstart = false
+	this.output("**" + markdownEscape(text) + "**")
+}
+
+func (this *markdownT) addCodeBlock(text estring) {
+	for text.startsWith("\n") {
+		text = text.mid(1, text.length()-1)
+	}
+	this.output("```cpp\n")
+	this.output(text)
+	if !text.endsWith("\n") {
+		this.output("\n")
+	}
+	this.output("```\n\n")
+}
+
+func (this *markdownT) addWarning(text estring) {
+	this.endParagraph()
+	this.output("> **Warning:** ")
+	this.addText(text)
+}
+
+func (this *markdownT) seeAlso(text estring) {
+	this.endParagraph()
+	this.output("**See also:** ")
+	this.addText(text)
+}
+
+func (this *markdownT) addNote(text estring) {
+	this.endParagraph()
+	this.output("> **Note:** ")
+	this.addText(text)
+}
+
+func (this *markdownT) addSection(prio int, text estring) {
+	if prio < 1 || prio > 3 {
+		panic(fmt.Sprintf("No support for prio level %d", prio))
+	}
+	this.endParagraph()
+	this.output(estring("####").mid(0, prio+1) + " ")
+	this.addText(text)
+	this.endParagraph()
+}
+
+/*! Write \a s to the output file. */
+
+func (this *markdownT) output(s estring) {
+	if this.fd == nil || s.isEmpty() {
+		return
+	}
+
+	this.fd.Write([]byte(s))
+}
+
+/*! Emits any boilerplate to be emitted at the end of each page. */
+
+func (this *markdownT) endPage() {
+	if this.fd == nil {
+		return
+	}
+
+	this.endParagraph()
+
+	this.output("---\n\nThis documentation is based on source code belonging to ")
+	if !output.ownerHome().isEmpty() {
+		this.addLink(output.ownerHome(), output.owner())
+	} else {
+		this.addText(output.owner())
+	}
+	this.output(". All rights reserved.\n")
+	this.para = ""
+	this.fd.Close()
+	this.fd = nil
+}
+
+/*! Starts a new Markdown file with base name \a name. */
+
+func (this *markdownT) startPage(name estring) {
+	this.names.clear()
+	this.fn = name
+	filename := this.directory + "/" + name + ".md"
+	var err error
+	this.fd, err = os.Create(string(filename))
+	if err != nil {
+		panic(fmt.Sprintf("Can't write %s: %s", filename, err))
+	}
+	this.para = ""
+}
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"log"
+	"sort"
+)
+
+/*! \class Namespace namespace.h
+  The Namespace class models a C++ namespace.
+
+  A Namespace is created whenever a named namespace block is seen in
+  a header or source file. Classes declared inside a namespace have
+  fully qualified names, e.g. "geo::Shape", and each Namespace which
+  contains documented classes or free functions gets a page listing
+  them.
+*/
+
+type Namespace struct {
+	n estring
+}
+
+var namespaces []*Namespace
+
+/*! Constructs a Namespace whose fully qualified name is \a name, and
+  any enclosing namespaces which don't exist yet. */
+
+func newNamespace(name estring) *Namespace {
+	if false {
+		log.Printf("New namespace: %s", name)
+	}
+	s := scopeOf(name)
+	if !s.isEmpty() && findNamespace(s) == nil {
+		newNamespace(s)
+	}
+	n := &Namespace{
+		n: name,
+	}
+	namespaces = append(namespaces, n)
+	return n
+}
+
+/*! Returns a pointer to the Namespace whose fully qualified name is
+  \a name, or a null pointer if there is no such namespace. */
+
+func findNamespace(name estring) *Namespace {
+	for _, n := range namespaces {
+		if n.n == name {
+			return n
+		}
+	}
+	return nil
+}
+
+/*! Returns the namespace's fully qualified name. */
+
+func (this Namespace) name() estring {
+	return this.n
+}
+
+/*! Returns the namespace enclosing this one, or a null pointer if
+  this is a top-level namespace. */
+
+func (this Namespace) parent() *Namespace {
+	return findNamespace(scopeOf(this.n))
+}
+
+/*! Returns the base name of this namespace's page. The prefix keeps
+  it from colliding with the page of a class with the same name. */
+
+func (this Namespace) pageName() estring {
+	return "namespace-" + pageName(this.n)
+}
+
+/*! Returns the documented classes declared directly in this
+  namespace, sorted by name. */
+
+func (this *Namespace) documentedClasses() []*Class {
+	var r []*Class
+	for _, c := range documentedClasses() {
+		if c.namespace() == this {
+			r = append(r, c)
+		}
+	}
+	return r
+}
+
+/*! Returns the documented free functions declared directly in this
+  namespace, except those which relate to a class, in the order they
+  were seen. */
+
+func (this *Namespace) functions() []*Function {
+	var r []*Function
+	for _, f := range functions {
+		if f.namespace() == this && f.relates() == nil &&
+			f.docBlock() != nil && !f.docBlock().isInternal() {
+			r = append(r, f)
+		}
+	}
+	return r
+}
+
+/*! Returns the namespaces declared directly in this one which
+  contain documented classes, functions or typedefs, sorted by
+  name. */
+
+func (this *Namespace) children() []*Namespace {
+	var r []*Namespace
+	for _, n := range namespaces {
+		if n.parent() == this && n.isDocumented() {
+			r = append(r, n)
+		}
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].n.lower() < r[j].n.lower()
+	})
+	return r
+}
+
+/*! Returns true if this namespace or one of the namespaces in it
+  contains a documented class, function or typedef, and false if
+  not. Only such namespaces have a page. */
+
+func (this *Namespace) isDocumented() bool {
+	return len(this.documentedClasses()) > 0 ||
+		len(this.functions()) > 0 || len(this.typedefs()) > 0 ||
+		len(this.children()) > 0
+}
+
+/*! Returns the documented typedefs declared directly in this
+  namespace, in the order they were seen. */
+
+func (this *Namespace) typedefs() []*Typedef {
+	var r []*Typedef
+	for _, td := range typedefs {
+		if td.namespace() == this && td.isDocumented() {
+			r = append(r, td)
+		}
+	}
+	return r
+}
+
+/*! Returns a list of the namespaces which have a page, sorted by
+  name. */
+
+func documentedNamespaces() []*Namespace {
+	var r []*Namespace
+	for _, n := range namespaces {
+		if n.isDocumented() {
+			r = append(r, n)
+		}
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].n.lower() < r[j].n.lower()
+	})
+	return r
+}
+
+/*! This static function generates a page for each namespace which
+  contains documented classes or functions.
+*/
+
+func outputNamespaces() {
+	for _, n := range documentedNamespaces() {
+		n.generateOutput()
+	}
+}
+
+/*! Generates the page for this namespace, which lists the namespaces
+  and classes in it and documents its free functions. */
+
+func (this *Namespace) generateOutput() {
+	output.startHeadlineNamespace(this)
+	output.addText("Namespace ")
+	output.addText(this.n)
+	output.addText(".")
+	output.endParagraph()
+
+	children := this.children()
+	if len(children) > 0 {
+		output.addText("Contains the namespaces ")
+		for idx, n := range children {
+			output.addNamespace(n.name(), n)
+			if idx == len(children)-1 {
+				output.addText(".")
+			} else if idx == len(children)-2 {
+				output.addText(" and ")
+			} else {
+				output.addText(", ")
+			}
+		}
+		output.endParagraph()
+	}
+
+	classes := this.documentedClasses()
+	if len(classes) > 0 {
+		output.addText("Contains the classes ")
+		for idx, c := range classes {
+			if idx == len(classes)-1 {
+				output.addClass(c.name()+".", c)
+			} else if idx == len(classes)-2 {
+				output.addClass(c.name(), c)
+				output.addText(" and ")
+			} else {
+				output.addClass(c.name()+",", c)
+				output.addText(" ")
+			}
+		}
+		output.endParagraph()
+	}
+
+	types := this.typedefs()
+	generateTypedefList("Contains the types ", types)
+	functions := this.functions()
+	generateFunctionList("Contains the functions ", functions)
+	for _, td := range types {
+		td.docBlock().generate()
+	}
+	for _, f := range functions {
+		f.docBlock().generate()
+	}
+}
+
+/*! Generates a paragraph which starts with \a intro and lists \a
+  functions, e.g. "Contains the functions swap() and
+  operator==()." Does nothing if \a functions is empty.
+*/
+
+func generateFunctionList(intro estring, functions []*Function) {
+	if len(functions) == 0 {
+		return
+	}
+	output.addText(intro)
+	for idx, f := range functions {
+		output.addFunction(unqualified(f.name())+"()", f)
+		if idx == len(functions)-1 {
+			output.addText(".")
+		} else if idx == len(functions)-2 {
+			output.addText(" and ")
+		} else {
+			output.addText(", ")
+		}
+	}
+	output.endParagraph()
+}
+
+/*! Generates a paragraph which starts with \a intro and lists \a
+  types, e.g. "Contains the types Handle and Callback." Does nothing
+  if \a types is empty.
+*/
+
+func generateTypedefList(intro estring, types []*Typedef) {
+	if len(types) == 0 {
+		return
+	}
+	output.addText(intro)
+	for idx, td := range types {
+		output.addTypedef(td.name(), td)
+		if idx == len(types)-1 {
+			output.addText(".")
+		} else if idx == len(types)-2 {
+			output.addText(" and ")
+		} else {
+			output.addText(", ")
+		}
+	}
+	output.endParagraph()
+}
+
+/*! Returns the scope part of the qualified name \a name, e.g. "geo"
+  for "geo::Shape", or an empty string if \a name is unqualified. A
+  "::" inside template arguments, as in "Foo<std::string>", doesn't
+  count. */
+
+func scopeOf(name estring) estring {
+	i := lastScopeSeparator(name)
+	if i < 0 {
+		return ""
+	}
+	return name.mid(0, i)
+}
+
+/*! Returns the last part of the qualified name \a name, e.g. "Shape"
+  for "geo::Shape". */
+
+func unqualified(name estring) estring {
+	i := lastScopeSeparator(name)
+	if i < 0 {
+		return name
+	}
+	return name.mid(i+2, name.length()-i-2)
+}
+
+/*! Returns the position of the last "::" in \a name which is not
+  inside template arguments, or -1 if there is none. */
+
+func lastScopeSeparator(name estring) int {
+	level := 0
+	i := name.length() - 1
+	for i > 0 {
+		if name[i] == '>' {
+			level++
+		} else if name[i] == '<' {
+			level--
+		} else if level == 0 && name[i] == ':' && name[i-1] == ':' {
+			return i - 1
+		}
+		i--
+	}
+	return -1
+}
+
+/*! Returns \a name without any template arguments, e.g. "Foo::Bar"
+  for "Foo<int>::Bar<T>". */
+
+func withoutTemplateArguments(name estring) estring {
+	var r estring
+	level := 0
+	for i := 0; i < name.length(); i++ {
+		if name[i] == '<' {
+			level++
+		} else if name[i] == '>' {
+			level--
+		} else if level == 0 {
+			r += estring(name[i])
+		}
+	}
+	return r
+}
+
+/*! Returns \a name qualified by \a scope, or just \a name if \a scope
+  is empty. */
+
+func qualified(scope, name estring) estring {
+	if scope.isEmpty() {
+		return name
+	}
+	return scope + "::" + name
+}
+
+/*! Returns a pointer to the Class called \a name as seen from \a
+  scope, which is the fully qualified name of a namespace or
+  class. The innermost match wins: "Shape" seen from "geo::Circle"
+  is "geo::Circle::Shape", "geo::Shape" or "Shape", whichever exists
+  first. A name with template arguments, e.g. "Foo<T>", names the
+  primary template unless there is a specialization with that exact
+  name. If none exist, but exactly one class is called \a name in
+  some namespace, that class is returned.
+
+  Returns a null pointer if no class is found.
+*/
+
+func lookupClass(name, scope estring) *Class {
+	if name.startsWith("::") {
+		return findClass(name.mid(2, name.length()-2))
+	}
+	s := scope
+	for {
+		c := findClass(qualified(s, name))
+		if c != nil {
+			return c
+		}
+		if s.isEmpty() {
+			break
+		}
+		s = scopeOf(s)
+	}
+	if name.contains("<") {
+		return lookupClass(withoutTemplateArguments(name), scope)
+	}
+	var r *Class
+	for _, c := range classes {
+		if c.name().endsWith("::" + name) {
+			if r != nil {
+				return nil
+			}
+			r = c
+		}
+	}
+	return r
+}
+
+/*! Returns a pointer to the Namespace called \a name as seen from
+  \a scope, which is the fully qualified name of a namespace. As for
+  lookupClass(), the innermost match wins. Returns a null pointer if
+  there is no such namespace.
+*/
+
+func lookupNamespace(name, scope estring) *Namespace {
+	if name.startsWith("::") {
+		return findNamespace(name.mid(2, name.length()-2))
+	}
+	for {
+		n := findNamespace(qualified(scope, name))
+		if n != nil {
+			return n
+		}
+		if scope.isEmpty() {
+			return nil
+		}
+		scope = scopeOf(scope)
+	}
+}
+
+/*! \class NamespaceTracker namespace.h
+  The NamespaceTracker class keeps track of which namespace blocks
+  are open at a given point in a file.
+
+  It scans the file from start to end as advance() is called,
+  skipping comments and literals as Parser does and counting braces,
+  and creates a Namespace object for each named namespace it sees.
+*/
+
+type NamespaceTracker struct {
+	t    estring
+	i    int
+	open estringlist
+	p    *Parser
+}
+
+/*! Constructs a NamespaceTracker for \a contents, positioned at its
+  start. */
+
+func newNamespaceTracker(contents estring) *NamespaceTracker {
+	return &NamespaceTracker{
+		t: contents,
+		p: newParser(contents),
+	}
+}
+
+/*! Scans forward to position \a to, noting each brace and namespace
+  block on the way.
+
+  Each open brace is recorded in a stack: namespace blocks with
+  their name, anonymous namespaces and extern "C" blocks with an
+  empty string, and other braces (class bodies, functions) with "{".
+*/
+
+func (this *NamespaceTracker) advance(to int) {
+	for this.i < to && this.i < this.t.length() {
+		c := this.t[this.i]
+		if j := this.p.commentOrLiteralAt(this.i); j > this.i {
+			this.i = j
+		} else if c == '{' {
+			this.open = append(this.open, "{")
+			this.i++
+		} else if c == '}' {
+			if len(this.open) > 0 {
+				this.open = this.open[:len(this.open)-1]
+			}
+			this.i++
+		} else if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			w := this.word()
+			if w == "namespace" {
+				this.namespaceBlock()
+			} else if w == "extern" {
+				this.space()
+				if this.t.at(this.i) == '"' {
+					this.i = this.p.commentOrLiteralAt(this.i)
+					this.space()
+					if this.t.at(this.i) == '{' {
+						this.open = append(this.open, "")
+						this.i++
+					}
+				}
+			}
+		} else {
+			this.i++
+		}
+	}
+}
+
+/*! Handles the part of a namespace definition after the keyword. A
+  namespace alias or using directive is skipped. */
+
+func (this *NamespaceTracker) namespaceBlock() {
+	this.space()
+	j := this.i
+	for this.i < this.t.length() && (this.t[this.i] == ':' ||
+		this.t[this.i] == '_' ||
+		(this.t[this.i] >= 'a' && this.t[this.i] <= 'z') ||
+		(this.t[this.i] >= 'A' && this.t[this.i] <= 'Z') ||
+		(this.t[this.i] >= '0' && this.t[this.i] <= '9')) {
+		this.i++
+	}
+	name := this.t.mid(j, this.i-j)
+	this.space()
+	if this.t.at(this.i) != '{' {
+		return
+	}
+	this.i++
+	this.open = append(this.open, name)
+	if !name.isEmpty() {
+		full := this.namespace()
+		if findNamespace(full) == nil {
+			newNamespace(full)
+		}
+	}
+}
+
+/*! Steps past the identifier at the cursor and returns it. */
+
+func (this *NamespaceTracker) word() estring {
+	j := this.i
+	for this.i < this.t.length() && (this.t[this.i] == '_' ||
+		(this.t[this.i] >= 'a' && this.t[this.i] <= 'z') ||
+		(this.t[this.i] >= 'A' && this.t[this.i] <= 'Z') ||
+		(this.t[this.i] >= '0' && this.t[this.i] <= '9')) {
+		this.i++
+	}
+	return this.t.mid(j, this.i-j)
+}
+
+/*! Steps past whitespace at the cursor. */
+
+func (this *NamespaceTracker) space() {
+	for this.i < this.t.length() && (this.t[this.i] == 32 ||
+		this.t[this.i] == 9 || this.t[this.i] == 13 ||
+		this.t[this.i] == 10) {
+		this.i++
+	}
+}
+
+/*! Returns the fully qualified name of the innermost open namespace,
+  or an empty string if the cursor is in the global namespace. */
+
+func (this NamespaceTracker) namespace() estring {
+	var r estring
+	for _, n := range this.open {
+		if n != "{" && !n.isEmpty() {
+			r = qualified(r, n)
+		}
+	}
+	return r
+}
+
+/*! Returns true if the cursor is inside a brace which does not
+  belong to a namespace, such as a class body, and false if it is
+  at namespace level. */
+
+func (this NamespaceTracker) nested() bool {
+	return this.open.contains("{")
+}
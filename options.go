@@ -0,0 +1,404 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*! \class Options options.h
+  The Options class holds the settings udoc runs with.
+
+  The settings come from a configuration file (see Config) and from
+  the command line, which takes precedence: Which directory trees to
+  scan for source files, where to write the output, which files to
+  include or exclude, which file name extensions denote C++ sources
+  and headers, and so on.
+
+  A subdirectory may contain its own configuration file, in which
+  case optionsFor() returns a modified copy for files in that
+  subtree.
+*/
+
+type optionsT struct {
+	inputs           []string
+	outputDir        string
+	owner            string
+	ownerHome        string
+	formats          []string
+	theme            string
+	templates        string
+	patternRoot      string
+	include          []string
+	exclude          []string
+	sourceExtensions []string
+	headerExtensions []string
+	stripMacros      []string
+	defines          []string
+	suppress         []string
+	access           []string
+	inlineReimp      bool
+}
+
+var options *optionsT = defaultOptions()
+
+/*! Returns an Options object which behaves as udoc always did: it
+  scans the current directory for .cpp files and writes HTML output
+  there. */
+
+func defaultOptions() *optionsT {
+	return &optionsT{
+		inputs:           []string{"."},
+		outputDir:        ".",
+		formats:          []string{"html"},
+		sourceExtensions: []string{".cpp"},
+		headerExtensions: []string{".h"},
+		access:           []string{"public", "protected", "private"},
+	}
+}
+
+/*! Returns a copy of this Options object, which may be modified
+  without affecting this one. */
+
+func (this *optionsT) clone() *optionsT {
+	o := *this
+	return &o
+}
+
+/*! The listFlag type is a command-line flag which may be given more
+  than once, and each of whose values may be a comma-separated list.
+*/
+
+type listFlag []string
+
+func (this *listFlag) String() string {
+	return strings.Join(*this, ",")
+}
+
+func (this *listFlag) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		s = strings.TrimSpace(s)
+		if s != "" {
+			*this = append(*this, s)
+		}
+	}
+	return nil
+}
+
+/*! parseOptions() returns errBadFlag for a malformed command line,
+  which the flag package has already reported along with the usage.
+*/
+
+var errBadFlag = errors.New("bad command-line flag")
+
+/*! Parses the command-line arguments \a args (not including the
+  program name) and returns the resulting Options, or an error if
+  \a args or the configuration file cannot be parsed.
+
+  The configuration file is the one named by -config, or else
+  udoc.json or udoc.toml in the current directory, if present.
+  Command-line flags override its settings.
+
+  Any non-flag arguments are input roots. If none are given (here
+  or in the configuration file), the current directory is scanned.
+*/
+
+func parseOptions(args []string) (*optionsT, error) {
+	o := defaultOptions()
+	fs := flag.NewFlagSet("udoc", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: udoc [options] [input-root ...]\n")
+		fs.PrintDefaults()
+	}
+	var configPath, outputDir, owner, ownerHome, theme, templateDir string
+	var inlineReimp bool
+	var include, exclude, sourceExtensions, headerExtensions, formats, access, defines listFlag
+	fs.StringVar(&configPath, "config", "",
+		"read settings from `file` (default udoc.json or udoc.toml)")
+	fs.StringVar(&outputDir, "o", o.outputDir,
+		"write documentation to `dir`")
+	fs.StringVar(&owner, "owner", "",
+		"`name` of the source code's owner, used in page footers")
+	fs.StringVar(&ownerHome, "owner-home", "",
+		"`url` of the owner's home page")
+	fs.StringVar(&theme, "theme", "",
+		"copy stylesheets and other assets from `dir` to the HTML output")
+	fs.StringVar(&templateDir, "templates", "",
+		"read HTML page templates from `dir`")
+	fs.BoolVar(&inlineReimp, "inline-reimp", false,
+		"repeat the inherited documentation of functions marked \\reimp")
+	fs.Var(&formats, "format",
+		"comma-separated output `formats` (default html)")
+	fs.Var(&include, "include",
+		"only scan files matching `pattern` (may be repeated)")
+	fs.Var(&exclude, "exclude",
+		"skip files and directories matching `pattern` (may be repeated)")
+	fs.Var(&sourceExtensions, "source-ext",
+		"comma-separated source file `extensions` (default .cpp)")
+	fs.Var(&headerExtensions, "header-ext",
+		"comma-separated header file `extensions` (default .h)")
+	fs.Var(&access, "access",
+		"only document members with these comma-separated access `levels` (default public,protected,private)")
+	fs.Var(&defines, "define",
+		"define `macro` (name or name=value) for #if in headers (may be repeated)")
+	err := fs.Parse(args)
+	if err == flag.ErrHelp {
+		return nil, err
+	} else if err != nil {
+		return nil, errBadFlag
+	}
+
+	if configPath == "" {
+		configPath = findConfig(".")
+	}
+	if configPath != "" {
+		c, err := readConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
+		err = o.apply(c, filepath.Dir(configPath), true)
+		if err != nil {
+			return nil, err
+		}
+		loadedConfigs[absolutePath(configPath)] = true
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "o":
+			o.outputDir = outputDir
+		case "owner":
+			o.owner = owner
+		case "owner-home":
+			o.ownerHome = ownerHome
+		case "format":
+			o.formats = formats
+		case "theme":
+			o.theme = theme
+		case "templates":
+			o.templates = templateDir
+		case "inline-reimp":
+			o.inlineReimp = inlineReimp
+		case "include":
+			o.include = include
+			o.patternRoot = ""
+		case "exclude":
+			o.exclude = exclude
+			o.patternRoot = ""
+		case "source-ext":
+			o.sourceExtensions = normalizedExtensions(sourceExtensions)
+		case "header-ext":
+			o.headerExtensions = normalizedExtensions(headerExtensions)
+		case "access":
+			o.access = access
+		case "define":
+			o.defines = defines
+		}
+	})
+	if fs.NArg() > 0 {
+		o.inputs = fs.Args()
+	}
+
+	err = o.validate()
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+/*! Returns an error if any of the settings in this Options object is
+  unusable, and nil if all is well. */
+
+func (this *optionsT) validate() error {
+	for _, p := range append(this.include, this.exclude...) {
+		_, err := filepath.Match(p, "")
+		if err != nil {
+			return fmt.Errorf("bad pattern %q: %s", p, err)
+		}
+	}
+	if len(this.formats) == 0 {
+		return fmt.Errorf("no output formats")
+	}
+	for _, f := range this.formats {
+		if !knownFormat(f) {
+			return fmt.Errorf("unknown output format %q", f)
+		}
+	}
+	for _, a := range this.access {
+		if a != "public" && a != "protected" && a != "private" {
+			return fmt.Errorf("unknown access level %q", a)
+		}
+	}
+	return nil
+}
+
+/*! Returns true if \a format names an output format udoc can
+  produce. */
+
+func knownFormat(format string) bool {
+	_, ok := outputFormats[format]
+	return ok
+}
+
+/*! Returns true if \a format is one of the formats udoc should
+  produce. */
+
+func (this *optionsT) wantsFormat(format string) bool {
+	for _, f := range this.formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+/*! Returns true if members with access level \a access ("public",
+  "protected" or "private") should be documented. */
+
+func (this *optionsT) documents(access estring) bool {
+	for _, a := range this.access {
+		if estring(a) == access {
+			return true
+		}
+	}
+	return false
+}
+
+/*! Returns \a exts with a leading dot added to each extension that
+  lacks one, so that "cc" and ".cc" mean the same. */
+
+func normalizedExtensions(exts []string) []string {
+	r := make([]string, 0, len(exts))
+	for _, e := range exts {
+		if !strings.HasPrefix(e, ".") {
+			e = "." + e
+		}
+		r = append(r, e)
+	}
+	return r
+}
+
+/*! Returns true if \a name ends with one of \a exts, and false if
+  not. */
+
+func hasExtension(name string, exts []string) bool {
+	for _, e := range exts {
+		if strings.HasSuffix(name, e) {
+			return true
+		}
+	}
+	return false
+}
+
+/*! Returns true if \a path, which is relative to the directory the
+  patterns apply to, matches any of \a patterns. A pattern matches
+  if it matches the entire path, the base name, or any leading
+  directory of \a path, so "third_party" excludes a whole subtree.
+*/
+
+func matchesAny(path string, patterns []string) bool {
+	path = filepath.ToSlash(path)
+	candidates := []string{path, filepath.Base(path)}
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' {
+			candidates = append(candidates, path[:i])
+		}
+	}
+	for _, p := range patterns {
+		for _, c := range candidates {
+			ok, _ := filepath.Match(p, c)
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*! Returns \a path relative to the directory this object's include
+  and exclude patterns apply to. That directory is the one
+  containing the configuration file which set the patterns, or \a
+  root if they came from the top-level configuration or the command
+  line.
+*/
+
+func (this *optionsT) relativePath(root, path string) string {
+	base := this.patternRoot
+	if base == "" {
+		base = root
+	}
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
+/*! Returns true if \a path, which is in input root \a root, is a
+  source file udoc should parse.
+*/
+
+func (this *optionsT) isSource(root, path string) bool {
+	if !hasExtension(path, this.sourceExtensions) {
+		return false
+	}
+	rel := this.relativePath(root, path)
+	if matchesAny(rel, this.exclude) {
+		return false
+	}
+	if len(this.include) > 0 && !matchesAny(rel, this.include) {
+		return false
+	}
+	return true
+}
+
+/*! Returns true if the directory \a path, which is in input root \a
+  root, should not be scanned at all. */
+
+func (this *optionsT) isExcludedDirectory(root, path string) bool {
+	rel := this.relativePath(root, path)
+	if rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	return matchesAny(rel, this.exclude)
+}
+
+/*! Returns true if \a name ends with one of the header file
+  extensions. */
+
+func (this *optionsT) isHeader(name estring) bool {
+	return hasExtension(string(name), this.headerExtensions)
+}
+
+/*! Walks each of the input roots and calls NewSourceFile() for each
+  source file found. Configuration files found on the way apply to
+  the directory containing them and its subdirectories.
+*/
+
+func (this *optionsT) scanInputs() error {
+	for _, root := range this.inputs {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			o := optionsFor(filepath.Dir(path))
+			if info.IsDir() {
+				if path != root && o.isExcludedDirectory(root, path) {
+					return filepath.SkipDir
+				}
+				return loadDirectoryConfig(path, o)
+			}
+			if o.isSource(root, path) {
+				NewSourceFile(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -6,14 +6,91 @@ var output *outputT = &outputT{}
   The Output class coordinates documentation output.
 
   It provides a number of static functions, each of which calls
-  eponymous functions in each of the concrete output classes. The only
-  output class currently is WebPage. PostScript and ManPage may be written
-  when arnt is bored or they seem useful.
+  eponymous functions in each of the registered OutputBackend
+  objects. The backends currently are WebPage, Markdown, ManPage and
+  JsonDump. PostScript may be written when arnt is bored or it seems
+  useful.
 */
 type outputT struct {
 	needSpace bool
 	o         estring
 	u         estring
+	backends  []OutputBackend
+}
+
+/*! \class OutputBackend output.h
+  The OutputBackend interface is implemented by each concrete output
+  class.
+
+  Output calls each function on every registered backend, after
+  taking care of the spacing between words. endPage() is called
+  once more when all documentation has been generated, so a backend
+  can finish its last file.
+*/
+type OutputBackend interface {
+	startHeadlineIntro(i *Intro)
+	startHeadlineClass(c *Class)
+	startHeadlineNamespace(n *Namespace)
+	startHeadlineFile(p *FilePage)
+	startHeadlineFunction(f *Function)
+	startHeadlineEnum(e *Enum)
+	startHeadlineVariable(v *Variable)
+	startHeadlineTypedef(t *Typedef)
+	endParagraph()
+	addText(text estring)
+	addLink(url, title estring)
+	addArgument(text estring)
+	addFunction(text estring, f *Function)
+	addClass(text estring, c *Class)
+	addNamespace(text estring, n *Namespace)
+	addEnum(text estring, e *Enum)
+	addEnumValue(text estring, e *Enum)
+	addVariable(text estring, v *Variable)
+	addTypedef(text estring, t *Typedef)
+	addCodeBlock(text estring)
+	addWarning(text estring)
+	seeAlso(text estring)
+	addNote(text estring)
+	addSection(prio int, text estring)
+	endPage()
+}
+
+/*! This map contains a constructor for each output format udoc
+  knows, indexed by the format's name. Each constructor returns a
+  backend which writes to files in the given directory.
+*/
+var outputFormats = map[string]func(dir estring) OutputBackend{
+	"html":     func(dir estring) OutputBackend { return newWebpage(dir) },
+	"markdown": func(dir estring) OutputBackend { return newMarkdown(dir) },
+	"man":      func(dir estring) OutputBackend { return newManpage(dir) },
+	"json":     func(dir estring) OutputBackend { return newJsonDump(dir) },
+}
+
+/*! Adds \a b to the list of backends which receive output. */
+func (this *outputT) addBackend(b OutputBackend) {
+	this.backends = append(this.backends, b)
+}
+
+/*! The indexWriter interface is implemented by backends which can
+  generate an index of all the documentation. Output::finish() calls
+  writeIndex() on those, after all other output has been generated.
+*/
+type indexWriter interface {
+	writeIndex()
+}
+
+/*! Tells all output devices that there will be no more output, so
+  they can write any index and finish their last page.
+*/
+func (this *outputT) finish() {
+	this.endParagraph()
+	for _, b := range this.backends {
+		ix, ok := b.(indexWriter)
+		if ok {
+			ix.writeIndex()
+		}
+		b.endPage()
+	}
 }
 
 /*! Starts a headline for \a i, with appropriate fonts etc. The
@@ -21,7 +98,9 @@ type outputT struct {
 */
 func (this *outputT) startHeadlineIntro(i *Intro) {
 	this.endParagraph()
-	webpage.startHeadlineIntro(i)
+	for _, b := range this.backends {
+		b.startHeadlineIntro(i)
+	}
 }
 
 /*! Starts a headline for \a c, with appropriate fonts etc. The
@@ -29,7 +108,29 @@ func (this *outputT) startHeadlineIntro(i *Intro) {
 */
 func (this *outputT) startHeadlineClass(c *Class) {
 	this.endParagraph()
-	webpage.startHeadlineClass(c)
+	for _, b := range this.backends {
+		b.startHeadlineClass(c)
+	}
+}
+
+/*! Starts a headline for \a n, with appropriate fonts etc. The
+  headline runs until endParagraph() is called.
+*/
+func (this *outputT) startHeadlineNamespace(n *Namespace) {
+	this.endParagraph()
+	for _, b := range this.backends {
+		b.startHeadlineNamespace(n)
+	}
+}
+
+/*! Starts a headline for \a p, with appropriate fonts etc. The
+  headline runs until endParagraph() is called.
+*/
+func (this *outputT) startHeadlineFile(p *FilePage) {
+	this.endParagraph()
+	for _, b := range this.backends {
+		b.startHeadlineFile(p)
+	}
 }
 
 /*! Starts a headline for \a f, with appropriate fonts etc. The
@@ -37,13 +138,47 @@ func (this *outputT) startHeadlineClass(c *Class) {
 */
 func (this *outputT) startHeadlineFunction(f *Function) {
 	this.endParagraph()
-	webpage.startHeadlineFunction(f)
+	for _, b := range this.backends {
+		b.startHeadlineFunction(f)
+	}
+}
+
+/*! Starts a headline for \a e, with appropriate fonts etc. The
+  headline runs until endParagraph() is called.
+*/
+func (this *outputT) startHeadlineEnum(e *Enum) {
+	this.endParagraph()
+	for _, b := range this.backends {
+		b.startHeadlineEnum(e)
+	}
+}
+
+/*! Starts a headline for \a v, with appropriate fonts etc. The
+  headline runs until endParagraph() is called.
+*/
+func (this *outputT) startHeadlineVariable(v *Variable) {
+	this.endParagraph()
+	for _, b := range this.backends {
+		b.startHeadlineVariable(v)
+	}
+}
+
+/*! Starts a headline for \a t, with appropriate fonts etc. The
+  headline runs until endParagraph() is called.
+*/
+func (this *outputT) startHeadlineTypedef(t *Typedef) {
+	this.endParagraph()
+	for _, b := range this.backends {
+		b.startHeadlineTypedef(t)
+	}
 }
 
 /*! Ends the current paragraph on all output devices. */
 func (this *outputT) endParagraph() {
 	this.needSpace = false
-	webpage.endParagraph()
+	for _, b := range this.backends {
+		b.endParagraph()
+	}
 }
 
 /*! Adds \a text as ordinary text to all output devices. */
@@ -52,7 +187,9 @@ func (this *outputT) addText(text estring) {
 		this.needSpace = false
 		this.addText(" ")
 	}
-	webpage.addText(text)
+	for _, b := range this.backends {
+		b.addText(text)
+	}
 }
 
 /*! Adds \a url and \a title as a link to all capable output devices. */
@@ -62,7 +199,9 @@ func (this *outputT) addLink(url, title estring) {
 		this.needSpace = false
 		this.addText(" ")
 	}
-	webpage.addLink(url, title)
+	for _, b := range this.backends {
+		b.addLink(url, title)
+	}
 }
 
 /*! Adds \a text as an argument name to all output devices. */
@@ -71,7 +210,9 @@ func (this *outputT) addArgument(text estring) {
 		this.needSpace = false
 		this.addText(" ")
 	}
-	webpage.addArgument(text)
+	for _, b := range this.backends {
+		b.addArgument(text)
+	}
 }
 
 /*! Adds a link to \a f titled \a text on all output devices. Each
@@ -82,7 +223,9 @@ func (this *outputT) addFunction(text estring, f *Function) {
 		this.needSpace = false
 		this.addText(" ")
 	}
-	webpage.addFunction(text, f)
+	for _, b := range this.backends {
+		b.addFunction(text, f)
+	}
 }
 
 /*! Adds a link to \a c titled \a text to all output devices. Each
@@ -93,7 +236,74 @@ func (this *outputT) addClass(text estring, c *Class) {
 		this.needSpace = false
 		this.addText(" ")
 	}
-	webpage.addClass(text, c)
+	for _, b := range this.backends {
+		b.addClass(text, c)
+	}
+}
+
+/*! Adds a link to \a n titled \a text to all output devices. Each
+  device may express the link differently.
+*/
+func (this *outputT) addNamespace(text estring, n *Namespace) {
+	if this.needSpace {
+		this.needSpace = false
+		this.addText(" ")
+	}
+	for _, b := range this.backends {
+		b.addNamespace(text, n)
+	}
+}
+
+/*! Adds a link to \a e titled \a text to all output devices. Each
+  device may express the link differently.
+*/
+func (this *outputT) addEnum(text estring, e *Enum) {
+	if this.needSpace {
+		this.needSpace = false
+		this.addText(" ")
+	}
+	for _, b := range this.backends {
+		b.addEnum(text, e)
+	}
+}
+
+/*! Adds the name \a text of a value of \a e, which is about to be
+  documented, to all output devices.
+*/
+func (this *outputT) addEnumValue(text estring, e *Enum) {
+	if this.needSpace {
+		this.needSpace = false
+		this.addText(" ")
+	}
+	for _, b := range this.backends {
+		b.addEnumValue(text, e)
+	}
+}
+
+/*! Adds a link to \a v titled \a text to all output devices. Each
+  device may express the link differently.
+*/
+func (this *outputT) addVariable(text estring, v *Variable) {
+	if this.needSpace {
+		this.needSpace = false
+		this.addText(" ")
+	}
+	for _, b := range this.backends {
+		b.addVariable(text, v)
+	}
+}
+
+/*! Adds a link to \a t titled \a text to all output devices. Each
+  device may express the link differently.
+*/
+func (this *outputT) addTypedef(text estring, t *Typedef) {
+	if this.needSpace {
+		this.needSpace = false
+		this.addText(" ")
+	}
+	for _, b := range this.backends {
+		b.addTypedef(text, t)
+	}
 }
 
 /*! Adds a code snippet \a text to all output devices. Each
@@ -101,7 +311,9 @@ func (this *outputT) addClass(text estring, c *Class) {
 */
 func (this *outputT) addCodeBlock(text estring) {
 	this.endParagraph()
-	webpage.addCodeBlock(text)
+	for _, b := range this.backends {
+		b.addCodeBlock(text)
+	}
 	this.endParagraph()
 }
 
@@ -113,7 +325,9 @@ func (this *outputT) addWarning(text estring) {
 		this.needSpace = false
 		this.addText(" ")
 	}
-	webpage.addWarning(text)
+	for _, b := range this.backends {
+		b.addWarning(text)
+	}
 }
 
 /*! Adds a see also block \a text to all output devices. Each
@@ -124,7 +338,9 @@ func (this *outputT) seeAlso(text estring) {
 		this.needSpace = false
 		this.addText(" ")
 	}
-	webpage.seeAlso(text)
+	for _, b := range this.backends {
+		b.seeAlso(text)
+	}
 }
 
 /*! Adds an emphasized note \a text to all output devices. Each
@@ -135,7 +351,9 @@ func (this *outputT) addNote(text estring) {
 		this.needSpace = false
 		this.addText(" ")
 	}
-	webpage.addNote(text)
+	for _, b := range this.backends {
+		b.addNote(text)
+	}
 }
 
 /*! Adds a section header of emphasis level \a prio with a given \a text
@@ -146,7 +364,9 @@ func (this *outputT) addSection(prio int, text estring) {
 		this.needSpace = false
 		this.addText(" ")
 	}
-	webpage.addSection(prio, text)
+	for _, b := range this.backends {
+		b.addSection(prio, text)
+	}
 }
 
 /*! Adds a single space to all output devices, prettily optimizing so
@@ -167,3 +387,124 @@ func (this *outputT) setOwnerHome(u estring) {
 func (this *outputT) ownerHome() estring {
 	return this.u
 }
+
+/*! Returns the start and length of the part of \a text which should
+  be made into a link to \a f. If part of \a text corresponds to the
+  name of \a f (or its member part), that part is used, otherwise all
+  of \a text is.
+*/
+func functionLinkSpan(text estring, f *Function) (int, int) {
+	name := f.name()
+	ll := text.length()
+	ls := text.find(name)
+	// if we don't find the complete function name, try just the member part
+	if ls < 0 {
+		i := name.length()
+		for i > 0 && name.at(i) != ':' {
+			i--
+		}
+		if i > 0 {
+			name = name.mid(i+1, len(name)-i+1)
+			ls = text.find(name)
+		}
+	}
+	if ls >= 0 {
+		ll = name.length()
+	} else {
+		ls = 0
+	}
+	if ll < text.length() && text.mid(ls+ll, 2) == "()" {
+		ll = ll + 2
+	}
+	return ls, ll
+}
+
+/*! Returns the start and length of the part of \a text which should
+  be made into a link to \a c: The part which corresponds to the
+  name of \a c, qualified as fully as in \a text, or all of \a text
+  if there is no such part.
+*/
+func classLinkSpan(text estring, c *Class) (int, int) {
+	n := c.name()
+	for {
+		ls := text.find(n)
+		if ls >= 0 {
+			return ls, n.length()
+		}
+		i := n.find("::")
+		if i < 0 {
+			break
+		}
+		n = n.mid(i+2, n.length()-i-2)
+	}
+	return 0, text.length()
+}
+
+/*! Returns the base name of the page documenting the class or
+  namespace \a name: \a name in lower case, with "-" instead of
+  "::", e.g. "geo-shape" for "geo::Shape". Template arguments are
+  kept, but with "-" instead of punctuation, e.g. "foo-int" for
+  "Foo<int>".
+*/
+func pageName(name estring) estring {
+	n := name.lower()
+	var r estring
+	i := 0
+	for i < n.length() {
+		if n[i] == ':' && n.at(i+1) == ':' {
+			r += "-"
+			i += 2
+		} else if n[i] == '<' || n[i] == ',' || n[i] == '*' || n[i] == '&' {
+			r += "-"
+			i++
+		} else if n[i] == '>' || n[i] == ' ' {
+			i++
+		} else {
+			r += estring(n[i])
+			i++
+		}
+	}
+	return r
+}
+
+/*! Returns the start and length of the part of \a text which should
+  be made into a link to \a t: The part which corresponds to the
+  name of \a t, qualified as fully as in \a text, or all of \a text
+  if there is no such part.
+*/
+func typedefLinkSpan(text estring, t *Typedef) (int, int) {
+	n := t.fullName()
+	for {
+		ls := text.find(n)
+		if ls >= 0 {
+			return ls, n.length()
+		}
+		i := n.find("::")
+		if i < 0 {
+			break
+		}
+		n = n.mid(i+2, n.length()-i-2)
+	}
+	return 0, text.length()
+}
+
+/*! Returns the start and length of the part of \a text which should
+  be made into a link to \a e: The part which corresponds to the name
+  of \a e or the name of one of its values, or all of \a text if
+  there is no such part.
+*/
+func enumLinkSpan(text estring, e *Enum) (int, int) {
+	if !e.name().isEmpty() {
+		ls := text.find(e.name())
+		if ls >= 0 {
+			return ls, e.name().length()
+		}
+	}
+	for _, v := range e.enumValues() {
+		ls := text.find(v.n)
+		if ls >= 0 {
+			return ls, v.n.length()
+		}
+	}
+	return 0, text.length()
+}
@@ -14,6 +14,10 @@ type Parser struct {
   It doesn't actually parse C++: all it does is lend some support to
   the header and source handling, which needs to find certain
   constructs and look at them.
+
+  It does know what comments, string literals and character literals
+  look like, though, and steps over them wherever it scans, so that
+  e.g. a ')' in a string doesn't end an argument list.
 */
 
 /*! Constructs a Parser for string \a s. The parser's cursor is left
@@ -56,22 +60,37 @@ func (this *Parser) line() int {
 }
 
 /*! Scans forward until an instance of \a text is found, and positions
-  the cursor at the first character after that string. */
+  the cursor at the first character after that string. Instances in
+  comments and literals are not found, unless \a text starts the
+  comment. */
 
 func (this *Parser) scan(text estring) {
-	j := 0
-	for this.i < this.t.length() && j < text.length() {
-		j = 0
-		for j < text.length() && this.t.at(this.i+j) == text[j] {
-			j++
+	j := this.find(text, this.i)
+	if j < 0 {
+		this.i = this.t.length()
+	} else {
+		this.i = j + text.length()
+	}
+}
+
+/*! Returns the position of the first instance of \a text at or after
+  \a j, or -1 if there is none. As for scan(), comments and literals
+  are skipped.
+*/
+
+func (this *Parser) find(text estring, j int) int {
+	for j < this.t.length() {
+		if this.t.mid(j, text.length()) == text {
+			return j
 		}
-		if j < text.length() {
-			this.i++
+		k := this.commentOrLiteralAt(j)
+		if k > j {
+			j = k
+		} else {
+			j++
 		}
 	}
-	if j == text.length() {
-		this.i += j
-	}
+	return -1
 }
 
 /*! Scans for \a text and returns all the text, without the trailing
@@ -86,6 +105,78 @@ func (this *Parser) textUntil(text estring) estring {
 	return this.t.mid(j, this.i-j-text.length())
 }
 
+/*! Like textUntil(), but treats the text as plain text rather than
+  C++, so e.g. an apostrophe doesn't start a character literal. This
+  is what's wanted inside a comment. */
+
+func (this *Parser) rawTextUntil(text estring) estring {
+	j := this.i
+	k := this.t.findAt(text, j)
+	if k < 0 {
+		this.i = this.t.length()
+		return this.t.mid(j, this.i-j)
+	}
+	this.i = k + text.length()
+	return this.t.mid(j, k-j)
+}
+
+/*! Returns the position after the comment, string literal or
+  character literal starting at \a j, or \a j if there is none.
+
+  An unterminated literal ends at the end of its line, and an
+  unterminated comment at the end of the text. The apostrophes in
+  numbers such as 1'000'000 are not taken as character literals.
+*/
+
+func (this *Parser) commentOrLiteralAt(j int) int {
+	c := this.t.at(j)
+	if c == '/' && this.t.at(j+1) == '/' {
+		k := this.t.findAt("\n", j)
+		if k < 0 {
+			return this.t.length()
+		}
+		return k
+	} else if c == '/' && this.t.at(j+1) == '*' {
+		k := this.t.findAt("*/", j+2)
+		if k < 0 {
+			return this.t.length()
+		}
+		return k + 2
+	} else if c != '"' && c != '\'' {
+		return j
+	}
+	s := j
+	for s > 0 && isIdentifierChar(this.t[s-1]) {
+		s--
+	}
+	if c == '\'' && s < j && this.t[s] >= '0' && this.t[s] <= '9' {
+		// a digit separator
+		return j
+	}
+	if c == '"' && s < j && this.t[j-1] == 'R' {
+		// a raw string literal, R"delimiter(...)delimiter"
+		d := this.t.findAt("(", j)
+		if d >= 0 {
+			k := this.t.findAt(")"+this.t.mid(j+1, d-j-1)+"\"", d)
+			if k >= 0 {
+				return k + d - j + 1
+			}
+		}
+		return this.t.length()
+	}
+	k := j + 1
+	for k < this.t.length() && this.t[k] != c && this.t[k] != '\n' {
+		if this.t[k] == '\\' {
+			k++
+		}
+		k++
+	}
+	if this.t.at(k) == c {
+		k++
+	}
+	return k
+}
+
 /*! Scans past whitespace, leaving the cursor at the end or at a
   nonwhitespace character.
 */
@@ -106,6 +197,27 @@ func spaceless(t estring) estring {
 	return r
 }
 
+/*! Steps past the block starting with the '{' at the cursor, up to
+  and including the matching '}', and any trailing whitespace. */
+
+func (this *Parser) block() {
+	level := 0
+	for level > 0 || this.lookingAt("{") {
+		if this.lookingAt("{") {
+			level++
+		} else if this.lookingAt("}") {
+			level--
+		}
+		j := this.commentOrLiteralAt(this.i)
+		if j > this.i {
+			this.i = j
+		} else {
+			this.step()
+		}
+		this.whitespace()
+	}
+}
+
 /*! Returns the C++ identifier at the cursor, or an empty string if
   there isn't any. Steps past the identifier and any trailing whitespace.
 */
@@ -130,10 +242,11 @@ func (this *Parser) simpleIdentifier(j int) int {
 	if this.t.mid(k, 8) == "operator" {
 		return this.operatorHack(k)
 	}
-	if (this.t.at(k) >= 'A' && this.t.at(k) <= 'z') ||
-		(this.t.at(k) >= 'a' && this.t.at(k) <= 'z') {
+	if (this.t.at(k) >= 'A' && this.t.at(k) <= 'Z') ||
+		(this.t.at(k) >= 'a' && this.t.at(k) <= 'z') ||
+		this.t.at(k) == '_' {
 		j = k + 1
-		for (this.t.at(j) >= 'A' && this.t.at(j) <= 'z') ||
+		for (this.t.at(j) >= 'A' && this.t.at(j) <= 'Z') ||
 			(this.t.at(j) >= 'a' && this.t.at(j) <= 'z') ||
 			(this.t.at(j) >= '0' && this.t.at(j) <= '9') ||
 			(this.t.at(j) == '_') {
@@ -162,7 +275,17 @@ func (this *Parser) complexIdentifier(j int) int {
 	}
 	j = this.whitespaceAt(l)
 
-	for this.t.at(j) == ':' && this.t.at(j+1) == ':' {
+	for {
+		if this.t.at(j) == '<' {
+			k = this.templateArgumentsAt(j)
+			if k == j {
+				break
+			}
+			j = this.whitespaceAt(k)
+		}
+		if this.t.at(j) != ':' || this.t.at(j+1) != ':' {
+			break
+		}
 		if this.t.mid(j+2, 8) == "operator" {
 			j = this.operatorHack(j + 2)
 		} else if this.t.at(j+2) == '~' {
@@ -170,18 +293,64 @@ func (this *Parser) complexIdentifier(j int) int {
 		} else {
 			j = this.simpleIdentifier(j + 2)
 		}
+		j = this.whitespaceAt(j)
 	}
+	return j
+}
 
-	j = this.whitespaceAt(j)
-	if this.t.at(j) == '<' {
-		k = this.complexIdentifier(j + 1)
-		if k > j+1 && this.t.at(k) == '>' {
-			j = k + 1
+/*! Scans past the template argument (or parameter) list starting
+  with the '<' at \a j, and returns the position after the matching
+  '>'. Nested lists and parentheses are skipped. If \a j does not
+  start a well-formed list, \a j is returned.
+*/
+
+func (this *Parser) templateArgumentsAt(j int) int {
+	k := j
+	level := 0
+	for k < this.t.length() {
+		c := this.t[k]
+		if c == '<' || c == '(' {
+			level++
+		} else if c == '>' || c == ')' {
+			level--
+			if level == 0 {
+				return k + 1
+			}
+		} else if c == ';' || c == '{' || c == '}' {
+			break
+		}
+		if l := this.commentOrLiteralAt(k); l > k {
+			k = l
+		} else {
+			k++
 		}
 	}
 	return j
 }
 
+/*! Parses and steps past a template header such as "template<class
+  T>", and returns its parameter list including the angle brackets,
+  e.g. "<class T>". Returns an empty string and leaves the cursor
+  alone if there is no template header at the cursor.
+*/
+
+func (this *Parser) templateParameters() estring {
+	j := this.whitespaceAt(this.i)
+	if this.t.mid(j, 8) != "template" {
+		return ""
+	}
+	k := this.whitespaceAt(j + 8)
+	if this.t.at(k) != '<' {
+		return ""
+	}
+	l := this.templateArgumentsAt(k)
+	if l == k {
+		return ""
+	}
+	this.i = this.whitespaceAt(l)
+	return this.t.mid(k, l-k).simplified()
+}
+
 /*! Parses a type name starting at \a j and returns the first
   character after the type name (and after trailing whitespace). If
   a type name can't be parsed, \a j is returned.
@@ -205,7 +374,13 @@ func (this *Parser) parseTypeAt(j int) int {
 			modifier == "class" ||
 			modifier == "struct" ||
 			modifier == "virtual" ||
-			modifier == "static") {
+			modifier == "static" ||
+			modifier == "volatile" ||
+			modifier == "typename" ||
+			modifier == "constexpr" ||
+			modifier == "consteval" ||
+			modifier == "explicit" ||
+			modifier == "mutable") {
 			l = k
 		}
 		if l <= k {
@@ -219,16 +394,21 @@ func (this *Parser) parseTypeAt(j int) int {
 	}
 
 	k = this.whitespaceAt(l)
-	if this.t.at(k) == ':' && this.t.at(k+1) == ':' {
-		l = this.whitespaceAt(this.simpleIdentifier(k + 2))
-		if l == k {
-			return j
-		}
-		k = l
-	}
 
-	if this.t.at(k) == '&' || this.t.at(k) == '*' {
-		k = this.whitespaceAt(k + 1)
+	// then any number of '*', '&' and "&&", possibly cv-qualified,
+	// e.g. "char * const *"
+	for {
+		if this.t.at(k) == '&' || this.t.at(k) == '*' {
+			k = this.whitespaceAt(k + 1)
+		} else if this.t.mid(k, 5) == "const" &&
+			!isIdentifierChar(this.t.at(k+5)) {
+			k = this.whitespaceAt(k + 5)
+		} else if this.t.mid(k, 8) == "volatile" &&
+			!isIdentifierChar(this.t.at(k+8)) {
+			k = this.whitespaceAt(k + 8)
+		} else {
+			break
+		}
 	}
 	return k
 }
@@ -258,14 +438,17 @@ func (this *Parser) parseType() estring {
 		case '*':
 			fallthrough
 		case '&':
-			s := make([]rune, 0, len(r))
-			for idx, c := range r {
-				if idx == tlen-2 {
-					if c == ' ' {
-						continue
-					}
+			// "char * *" and "Widget &&" become "char**" and "Widget&&"
+			i := tlen
+			for i > 0 && (r[i-1] == '*' || r[i-1] == '&' || r[i-1] == ' ') {
+				i--
+			}
+			s := make([]byte, 0, tlen)
+			s = append(s, r[:i]...)
+			for _, c := range []byte(r[i:]) {
+				if c != ' ' {
+					s = append(s, c)
 				}
-				s = append(s, c)
 			}
 			r = estring(s)
 		}
@@ -310,10 +493,8 @@ func (this *Parser) argumentList() estring {
 		r = r + s + tp
 		this.whitespace()
 		if this.t.at(this.i) == '=' { // there is a default value...
-			for this.i < this.t.length() && this.t.at(this.i) != ',' && this.t.at(this.i) != ')' {
-				this.i++
-			}
-			this.whitespace()
+			this.step()
+			this.initializer()
 		} else if this.t.at(this.i) == '[' && this.t.at(this.i+1) == ']' { // this argument is an array
 			this.i = this.i + 2
 			r += "[]"
@@ -357,9 +538,10 @@ func (this *Parser) word() estring {
 	j := this.simpleIdentifier(this.i)
 	for this.t.at(j) == '-' {
 		k := this.simpleIdentifier(j + 1)
-		if k > j+1 {
-			j = k
+		if k <= j+1 {
+			break
 		}
+		j = k
 	}
 	r := this.t.mid(this.i, j-this.i).simplified()
 	if !r.isEmpty() {
@@ -368,6 +550,145 @@ func (this *Parser) word() estring {
 	return r
 }
 
+/*! Parses and steps past the specifiers which may precede a
+  declaration's type, and returns them in the order they were
+  written: any of "virtual", "static", "mutable", "inline",
+  "explicit", "constexpr", "consteval" and "constinit", and
+  attributes such as "[[nodiscard]]". A conditional explicit is
+  returned along with its condition, e.g. "explicit(false)".
+*/
+
+func (this *Parser) leadingSpecifiers() estringlist {
+	var r estringlist
+	for {
+		this.whitespace()
+		start := this.i
+		if this.lookingAt("[[") {
+			this.i = this.balancedAt(this.i)
+			if this.i == start {
+				break
+			}
+			r = append(r, this.t.mid(start, this.i-start).simplified())
+			continue
+		}
+		w := this.word()
+		if w == "explicit" {
+			j := this.whitespaceAt(this.i)
+			if k := this.balancedAt(j); k > j {
+				w += this.t.mid(j, k-j).simplified()
+				this.i = k
+			}
+		} else if w != "virtual" && w != "static" && w != "mutable" &&
+			w != "inline" && w != "constexpr" && w != "consteval" &&
+			w != "constinit" {
+			this.i = start
+			break
+		}
+		r = append(r, w)
+	}
+	return r
+}
+
+/*! Parses and steps past the specifiers which may follow a member
+  function's argument list and constness, and returns them: a
+  ref-qualifier ("&" or "&&"), "noexcept" (possibly with a
+  condition), a trailing return type written as e.g. "-> int",
+  "override" and "final", and then "= 0", "= default" or "= delete".
+*/
+
+func (this *Parser) trailingSpecifiers() estringlist {
+	var r estringlist
+	for {
+		this.whitespace()
+		start := this.i
+		w := this.word()
+		if w == "override" || w == "final" {
+			r = append(r, w)
+			continue
+		} else if w == "noexcept" {
+			j := this.whitespaceAt(this.i)
+			if k := this.balancedAt(j); k > j {
+				w += this.t.mid(j, k-j).simplified()
+				this.i = k
+			}
+			r = append(r, w)
+			continue
+		}
+		this.i = start
+		if this.lookingAt("&&") || this.lookingAt("&") {
+			w = "&"
+			if this.lookingAt("&&") {
+				w = "&&"
+			}
+			this.i += w.length()
+			r = append(r, w)
+			continue
+		} else if this.lookingAt("->") {
+			this.i += 2
+			t := this.parseType()
+			if k := this.balancedAt(this.i); k > this.i {
+				// e.g. "decltype(a + b)"
+				t += this.t.mid(this.i, k-this.i).simplified()
+				this.i = k
+			}
+			if t.isEmpty() {
+				this.i = start
+				break
+			}
+			r = append(r, "-> "+t)
+			continue
+		}
+		if !this.lookingAt("=") {
+			break
+		}
+		this.step()
+		this.whitespace()
+		if this.lookingAt("0") {
+			this.step()
+			r = append(r, "= 0")
+		} else {
+			w = this.word()
+			if w != "default" && w != "delete" {
+				this.i = start
+				break
+			}
+			r = append(r, "= "+w)
+		}
+	}
+	return r
+}
+
+/*! Steps past the parenthesised or bracketed text starting at \a
+  j, including anything nested in it, and returns the position after
+  the closing ')' or ']'. If \a j isn't at '(' or '[', or the text
+  isn't closed, \a j is returned.
+*/
+
+func (this *Parser) balancedAt(j int) int {
+	if this.t.at(j) != '(' && this.t.at(j) != '[' {
+		return j
+	}
+	k := j
+	level := 0
+	for k < this.t.length() {
+		c := this.t[k]
+		if c == '(' || c == '[' {
+			level++
+		} else if c == ')' || c == ']' {
+			level--
+			if level == 0 {
+				return k + 1
+			}
+		}
+		if l := this.commentOrLiteralAt(k); l > k {
+			k = l
+		} else {
+			k++
+		}
+	}
+	return j
+}
+
 /*! Parses and steps past a single value, which is either a number or
   an identifier.
 */
@@ -390,8 +711,39 @@ func (this *Parser) value() estring {
 	return this.identifier()
 }
 
+/*! Parses and steps past an initializer, such as an enum value's,
+  and returns it simplified. The initializer ends at the first ',',
+  ')', '}' or ';' which isn't nested inside parentheses, brackets or
+  braces; the cursor is left there.
+*/
+
+func (this *Parser) initializer() estring {
+	j := this.whitespaceAt(this.i)
+	k := j
+	level := 0
+	for k < this.t.length() {
+		c := this.t[k]
+		if c == '(' || c == '[' || c == '{' {
+			level++
+		} else if level > 0 && (c == ')' || c == ']' || c == '}') {
+			level--
+		} else if level == 0 &&
+			(c == ',' || c == ')' || c == '}' || c == ';') {
+			break
+		}
+		if l := this.commentOrLiteralAt(k); l > k {
+			k = l
+		} else {
+			k++
+		}
+	}
+	this.i = k
+	return this.t.mid(j, k-j).simplified()
+}
+
 /*! Steps past the whitespace starting at \a j and return the index of
-  the first following nonwhitespace character.
+  the first following nonwhitespace character. Comments count as
+  whitespace, except those starting with "/*!", which udoc has to see.
 */
 
 func (this *Parser) whitespaceAt(j int) int {
@@ -403,10 +755,9 @@ func (this *Parser) whitespaceAt(j int) int {
 			j++
 		}
 
-		if this.t.at(j) == '/' && this.t.at(j+1) == '/' {
-			for j < this.t.length() && this.t.at(j) != '\n' {
-				j++
-			}
+		if this.t.at(j) == '/' && (this.t.at(j+1) == '/' ||
+			(this.t.at(j+1) == '*' && this.t.at(j+2) != '!')) {
+			j = this.commentOrLiteralAt(j)
 		}
 
 		if j <= k {
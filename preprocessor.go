@@ -0,0 +1,408 @@
+package main
+
+import (
+	"strconv"
+)
+
+/*! \class Preprocessor preprocessor.h
+  The Preprocessor class does just enough C preprocessing for the
+  header parser to see what the compiler sees.
+
+  It evaluates #if, #ifdef, #ifndef, #elif and #else against the
+  configured defines and any #define and #undef it sees on the way,
+  and blanks out the lines which would not be compiled. Directive
+  lines themselves (including #include and #pragma) are blanked
+  too. Macros are not expanded; stripMacros() removes the ones which
+  would confuse the parser.
+
+  Line breaks are always kept, so that line numbers in the result
+  are those of the original file.
+*/
+
+type Preprocessor struct {
+	f       File
+	defines map[string]estring
+	active  bool
+	stack   []ppState
+}
+
+/*! The ppState type records one open #if: whether the text around
+  it is active, whether one of its branches has been taken, and
+  whether the current branch is active. */
+
+type ppState struct {
+	outer  bool
+	taken  bool
+	active bool
+}
+
+/*! Constructs a Preprocessor for \a f, with the macros in \a defines
+  defined. Each is either a name, which is defined as 1, or
+  "name=value".
+*/
+
+func newPreprocessor(f File, defines []string) *Preprocessor {
+	p := &Preprocessor{
+		f:       f,
+		defines: make(map[string]estring),
+		active:  true,
+	}
+	// udoc documents C++, so headers should see the C++ branches
+	p.defines["__cplusplus"] = "201103L"
+	for _, d := range defines {
+		n := estring(d)
+		v := estring("1")
+		if i := n.find("="); i >= 0 {
+			v = n.mid(i+1, n.length()-i-1)
+			n = n.mid(0, i)
+		}
+		p.defines[string(n.simplified())] = v.simplified()
+	}
+	return p
+}
+
+/*! Returns \a contents, preprocessed as described above. */
+
+func (this *Preprocessor) process(contents estring) estring {
+	r := make([]byte, 0, contents.length())
+	i := 0
+	l := 1
+	for i < contents.length() {
+		e := i
+		for e < contents.length() && contents[e] != '\n' {
+			if contents[e] == '\\' && contents.at(e+1) == '\n' {
+				// a continued line
+				e++
+			}
+			e++
+		}
+		line := contents.mid(i, e-i)
+		var breaks estring
+		for _, c := range line {
+			if c == '\n' {
+				breaks += "\n"
+			}
+		}
+		if line.simplified().startsWith("#") {
+			this.directive(line, l)
+			line = breaks
+		} else if !this.active {
+			line = breaks
+		}
+		r = append(r, line...)
+		if e < contents.length() {
+			r = append(r, '\n')
+		}
+		l += breaks.length() + 1
+		i = e + 1
+	}
+	if len(this.stack) > 0 {
+		docError(this.f, l-1, "Missing #endif")
+	}
+	return estring(r)
+}
+
+/*! Handles the directive \a line, which is on line \a l. */
+
+func (this *Preprocessor) directive(line estring, l int) {
+	t := spacedLine(line).simplified()
+	t = t.mid(1, t.length()-1).simplified()
+	i := 0
+	for i < t.length() && isIdentifierChar(t[i]) {
+		i++
+	}
+	d := t.mid(0, i)
+	arg := t.mid(i, t.length()-i).simplified()
+	switch d {
+	case "if", "ifdef", "ifndef":
+		s := ppState{outer: this.active}
+		if this.active {
+			s.active = this.condition(d, arg, l)
+		}
+		s.taken = s.active
+		this.stack = append(this.stack, s)
+	case "elif", "else":
+		if len(this.stack) == 0 {
+			docError(this.f, l, "#"+d+" without #if")
+			return
+		}
+		s := &this.stack[len(this.stack)-1]
+		s.active = false
+		if s.outer && !s.taken {
+			s.active = d == "else" || this.condition("if", arg, l)
+		}
+		if s.active {
+			s.taken = true
+		}
+	case "endif":
+		if len(this.stack) == 0 {
+			docError(this.f, l, "#endif without #if")
+			return
+		}
+		this.stack = this.stack[:len(this.stack)-1]
+	case "define":
+		if this.active {
+			j := 0
+			for j < arg.length() && isIdentifierChar(arg[j]) {
+				j++
+			}
+			v := arg.mid(j, arg.length()-j).simplified()
+			if arg.at(j) == '(' {
+				// a function-like macro has no useful value
+				v = ""
+			}
+			this.defines[string(arg.mid(0, j))] = v
+		}
+	case "undef":
+		if this.active {
+			delete(this.defines, string(arg))
+		}
+	}
+	this.active = true
+	if len(this.stack) > 0 {
+		this.active = this.stack[len(this.stack)-1].active
+	}
+}
+
+/*! Returns true if the condition \a arg of the directive \a d (one of
+  "if", "ifdef" and "ifndef") on line \a l holds, and false if not.
+  A condition which cannot be evaluated is reported and considered
+  false. */
+
+func (this *Preprocessor) condition(d, arg estring, l int) bool {
+	if d == "ifdef" {
+		_, ok := this.defines[string(arg)]
+		return ok
+	} else if d == "ifndef" {
+		_, ok := this.defines[string(arg)]
+		return !ok
+	}
+	e := &ppExpression{t: arg, defines: this.defines}
+	v, ok := e.or()
+	e.space()
+	if !ok || e.i < e.t.length() {
+		docError(this.f, l, "Cannot evaluate #if "+arg)
+		return false
+	}
+	return v != 0
+}
+
+/*! Returns \a line with continuations and comments replaced by
+  spaces. */
+
+func spacedLine(line estring) estring {
+	var r estring
+	i := 0
+	for i < line.length() {
+		if line[i] == '\\' && line.at(i+1) == '\n' {
+			r += " "
+			i += 2
+		} else if line[i] == '/' && line.at(i+1) == '/' {
+			break
+		} else if line[i] == '/' && line.at(i+1) == '*' {
+			j := line.findAt("*/", i+2)
+			if j < 0 {
+				break
+			}
+			r += " "
+			i = j + 2
+		} else {
+			r += estring(line[i])
+			i++
+		}
+	}
+	return r
+}
+
+/*! The ppExpression type evaluates the expression of an #if
+  directive. It understands integers, defined(), macros defined as
+  integers, parentheses and the logical, comparison and additive
+  operators. Unknown identifiers are 0, as in C.
+*/
+
+type ppExpression struct {
+	t       estring
+	i       int
+	defines map[string]estring
+	depth   int
+}
+
+func (this *ppExpression) space() {
+	for this.t.at(this.i) == ' ' || this.t.at(this.i) == '\t' {
+		this.i++
+	}
+}
+
+/*! Steps past \a op and returns true if it is next, and returns
+  false otherwise. */
+
+func (this *ppExpression) operator(op estring) bool {
+	this.space()
+	if this.t.mid(this.i, op.length()) != op {
+		return false
+	}
+	// "<" mustn't match "<=", nor "!" "!="
+	n := this.t.at(this.i + op.length())
+	if op.length() == 1 && (n == '=' || n == op[0]) &&
+		op != "(" && op != ")" {
+		return false
+	}
+	this.i += op.length()
+	return true
+}
+
+func (this *ppExpression) or() (int64, bool) {
+	v, ok := this.and()
+	for ok && this.operator("||") {
+		var w int64
+		w, ok = this.and()
+		if v != 0 || w != 0 {
+			v = 1
+		}
+	}
+	return v, ok
+}
+
+func (this *ppExpression) and() (int64, bool) {
+	v, ok := this.comparison()
+	for ok && this.operator("&&") {
+		var w int64
+		w, ok = this.comparison()
+		if v != 0 && w != 0 {
+			v = 1
+		} else {
+			v = 0
+		}
+	}
+	return v, ok
+}
+
+func (this *ppExpression) comparison() (int64, bool) {
+	v, ok := this.sum()
+	for ok {
+		var op estring
+		for _, o := range []estring{"==", "!=", "<=", ">=", "<", ">"} {
+			if this.operator(o) {
+				op = o
+				break
+			}
+		}
+		if op.isEmpty() {
+			break
+		}
+		var w int64
+		w, ok = this.sum()
+		r := false
+		switch op {
+		case "==":
+			r = v == w
+		case "!=":
+			r = v != w
+		case "<=":
+			r = v <= w
+		case ">=":
+			r = v >= w
+		case "<":
+			r = v < w
+		case ">":
+			r = v > w
+		}
+		v = 0
+		if r {
+			v = 1
+		}
+	}
+	return v, ok
+}
+
+func (this *ppExpression) sum() (int64, bool) {
+	v, ok := this.unary()
+	for ok {
+		if this.operator("+") {
+			var w int64
+			w, ok = this.unary()
+			v += w
+		} else if this.operator("-") {
+			var w int64
+			w, ok = this.unary()
+			v -= w
+		} else {
+			break
+		}
+	}
+	return v, ok
+}
+
+func (this *ppExpression) unary() (int64, bool) {
+	if this.operator("!") {
+		v, ok := this.unary()
+		if v == 0 {
+			return 1, ok
+		}
+		return 0, ok
+	} else if this.operator("-") {
+		v, ok := this.unary()
+		return -v, ok
+	} else if this.operator("(") {
+		v, ok := this.or()
+		if !this.operator(")") {
+			return 0, false
+		}
+		return v, ok
+	}
+	this.space()
+	s := this.i
+	for isIdentifierChar(this.t.at(this.i)) {
+		this.i++
+	}
+	w := this.t.mid(s, this.i-s)
+	if w.isEmpty() {
+		return 0, false
+	} else if w[0] >= '0' && w[0] <= '9' {
+		return ppNumber(w)
+	} else if w == "defined" {
+		paren := this.operator("(")
+		this.space()
+		s = this.i
+		for isIdentifierChar(this.t.at(this.i)) {
+			this.i++
+		}
+		_, ok := this.defines[string(this.t.mid(s, this.i-s))]
+		if paren && !this.operator(")") {
+			return 0, false
+		}
+		if ok {
+			return 1, true
+		}
+		return 0, true
+	} else if w == "true" {
+		return 1, true
+	}
+	v, ok := this.defines[string(w)]
+	if !ok || v.isEmpty() || this.depth > 8 {
+		return 0, true
+	}
+	// a macro defined as another expression, e.g. "(FOO + 1)"
+	e := &ppExpression{t: v, defines: this.defines, depth: this.depth + 1}
+	r, ok := e.or()
+	e.space()
+	if !ok || e.i < e.t.length() {
+		return 0, true
+	}
+	return r, true
+}
+
+/*! Returns the value of the integer literal \a w, e.g. "0x0501" or
+  "42L", and true, or 0 and false if \a w is not an integer. */
+
+func ppNumber(w estring) (int64, bool) {
+	for w.length() > 1 && (w.endsWith("L") || w.endsWith("l") ||
+		w.endsWith("U") || w.endsWith("u")) {
+		w = w.mid(0, w.length()-1)
+	}
+	v, err := strconv.ParseInt(string(w), 0, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
@@ -4,14 +4,17 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 )
 
 /*! \class SourceFile sourcefile.h
   The SourceFile class models a C++ source file.
 
   When a SourceFile object is created, it automatically scans the file
-  for documented classes and functions, scans HeaderFile files as directed
-  and creates Class and Function objects.
+  for documented classes, enums, functions and variables, scans
+  HeaderFile files as directed and creates Class and Function
+  objects.
 
   That's all.
 */
@@ -61,6 +64,29 @@ func (this *SourceFile) findHeaderFromCandidates(candidates []estring, l int) es
 	return ""
 }
 
+/*! Parses the headers this file includes using '#include "..."',
+  if they exist relative to this file and haven't been parsed
+  already.
+*/
+
+func (this *SourceFile) parseIncludes() {
+	p := newParser(this.contents)
+	for {
+		p.scan("#include \"")
+		if p.atEnd() {
+			return
+		}
+		hn := p.textUntil("\"")
+		if findHeaderFile(hn) != nil {
+			continue
+		}
+		fn := filepath.Join(filepath.Dir(string(this.Name())), string(hn))
+		if _, err := os.Stat(fn); err == nil {
+			newHeaderFile(estring(fn))
+		}
+	}
+}
+
 /*! This happy-happy little function parse (or scans, to be truthful)
   a C++ source file looking for documentation. It's the All of this
   class.
@@ -69,21 +95,27 @@ func (this *SourceFile) findHeaderFromCandidates(candidates []estring, l int) es
 func (this *SourceFile) Parse() {
 	any := false
 	p := newParser(this.contents)
+	tracker := newNamespaceTracker(this.contents)
 	pfx := estring("/")
 	pfx += "*!" // must not see this as one string
 	p.scan(pfx)
 	for !p.atEnd() {
 		any = true
+		tracker.advance(p.i - pfx.length())
+		ns := tracker.namespace()
 		p.whitespace()
 		var f *Function
 		var c *Class
+		var e *Enum
+		var v *Variable
+		var td *Typedef
 		var i *Intro
 		var d estring
 		l := p.line()
 		if p.lookingAt("\\fn ") {
 			p.scan(" ")
-			f = this.function(p)
-			d = p.textUntil("*/")
+			f = this.function(p, ns)
+			d = p.rawTextUntil("*/")
 		} else if p.lookingAt("\\chapter ") {
 			p.scan(" ")
 			name := p.word()
@@ -92,17 +124,13 @@ func (this *SourceFile) Parse() {
 			}
 			i = newIntro(name)
 			p.whitespace()
-			d = p.textUntil("*/")
+			d = p.rawTextUntil("*/")
 		} else if p.lookingAt("\\class ") {
 			p.scan(" ")
 			className := p.identifier()
 			if className.isEmpty() {
 				docError(this, l, "\\class must be followed by a class name")
 			}
-			c = findClass(className)
-			if c == nil {
-				c = newClass(className, nil, 0)
-			}
 			p.whitespace()
 			hn := p.word()
 			for p.lookingAt(".") {
@@ -111,12 +139,20 @@ func (this *SourceFile) Parse() {
 				hn += p.word()
 			}
 			var headerCandidates []estring
-			if hn.length() < 2 || hn.mid(hn.length()-2, hn.length()) != ".h" {
+			o := optionsFor(filepath.Dir(string(this.name)))
+			if !o.isHeader(hn) {
 				//docError(this, l, "Missing header file name")
-				headerCandidates = append(headerCandidates, className.lower()+".h")
-				headerCandidates = append(headerCandidates, className+".h")
-				headerCandidates = append(headerCandidates, className.lower()+"_p.h")
-				headerCandidates = append(headerCandidates, className+"_p.h")
+				// a nested class may be in its outer class's header
+				for n := className; !n.isEmpty(); n = scopeOf(n) {
+					base := withoutTemplateArguments(unqualified(n))
+					for _, ext := range o.headerExtensions {
+						e := estring(ext)
+						headerCandidates = append(headerCandidates, base.lower()+e)
+						headerCandidates = append(headerCandidates, base+e)
+						headerCandidates = append(headerCandidates, base.lower()+"_p"+e)
+						headerCandidates = append(headerCandidates, base+"_p"+e)
+					}
+				}
 			} else {
 				headerCandidates = append(headerCandidates, hn)
 			}
@@ -136,16 +172,50 @@ func (this *SourceFile) Parse() {
 					docError(this, l, "Cannot find header file "+hn+" (for class "+className+")")
 				}
 			}
-			if len(c.members()) == 0 {
+			// the header tells us which namespace the class is in
+			c = lookupClass(className, ns)
+			if c == nil {
+				c = newClass(qualified(ns, className), nil, 0)
+			}
+			if len(c.members()) == 0 && len(c.variables()) == 0 {
 				docError(this, l, "Cannot find any "+className+" members in "+hn)
 			}
-			d = p.textUntil("*/")
+			d = p.rawTextUntil("*/")
+		} else if p.lookingAt("\\enum ") {
+			p.scan(" ")
+			n := p.identifier()
+			e = findEnum(n, ns)
+			if e == nil {
+				docError(this, l, "Cannot find enum "+n)
+			}
+			d = p.rawTextUntil("*/")
+		} else if p.lookingAt("\\var ") {
+			p.scan(" ")
+			n := p.identifier()
+			v = findVariable(n, ns)
+			if v == nil {
+				docError(this, l, "Cannot find member variable "+n)
+			}
+			d = p.rawTextUntil("*/")
+		} else if p.lookingAt("\\typedef ") {
+			p.scan(" ")
+			n := p.identifier()
+			td = lookupTypedef(n, ns)
+			if td == nil {
+				// it may be in a header no \class has led to
+				this.parseIncludes()
+				td = lookupTypedef(n, ns)
+			}
+			if td == nil {
+				docError(this, l, "Cannot find typedef "+n)
+			}
+			d = p.rawTextUntil("*/")
 		} else if p.lookingAt("\\nodoc") {
 			any = true
 			d = "hack"
 		} else {
-			d = p.textUntil("*/")
-			f = this.function(p)
+			d = p.rawTextUntil("*/")
+			f = this.function(p, ns)
 		}
 		if d.isEmpty() {
 			docError(this, l, "Comment contains no documentation")
@@ -153,6 +223,12 @@ func (this *SourceFile) Parse() {
 			newDocBlockForFunction(this, l, d, f)
 		} else if c != nil {
 			newDocBlockForClass(this, l, d, c)
+		} else if e != nil {
+			newDocBlockForEnum(this, l, d, e)
+		} else if v != nil {
+			newDocBlockForVariable(this, l, d, v)
+		} else if td != nil {
+			newDocBlockForTypedef(this, l, d, td)
 		} else if i != nil {
 			newDocBlockForIntro(this, l, d, i)
 		}
@@ -173,11 +249,25 @@ func (this *SourceFile) Parse() {
 
 /*! This helper parses a function name using \a p or reports an
   error. It returns a pointer to the function, or a null pointer in
-  case of error.
+  case of error. \a ns is the namespace the definition is in; an
+  unqualified name is that of a free function in \a ns.
+
+  Template headers before the definition are noted; for a member of
+  a class template, the first one belongs to the class. So are the
+  specifiers of a function udoc hasn't seen declared in a header.
 */
 
-func (this *SourceFile) function(p *Parser) *Function {
+func (this *SourceFile) function(p *Parser, ns estring) *Function {
 	var f *Function
+	var templates estringlist
+	for {
+		tp := p.templateParameters()
+		if tp.isEmpty() {
+			break
+		}
+		templates = append(templates, tp)
+	}
+	specifiers := p.leadingSpecifiers()
 	t := p.parseType()
 	l := p.line()
 	n := p.identifier()
@@ -193,13 +283,32 @@ func (this *SourceFile) function(p *Parser) *Function {
 		p.word()
 		cn = true
 	}
-	if !n.isEmpty() && n.find(":") > 0 &&
-		!a.isEmpty() {
+	specifiers = append(specifiers, p.trailingSpecifiers()...)
+	if !n.isEmpty() && !a.isEmpty() {
+		if scopeOf(n).isEmpty() {
+			// a free function in the current namespace
+			n = qualified(ns, n)
+		} else if c := lookupClass(scopeOf(n), ns); c != nil {
+			n = c.name() + "::" + unqualified(n)
+		} else if o := lookupNamespace(scopeOf(n), ns); o != nil {
+			n = o.name() + "::" + unqualified(n)
+		} else {
+			n = qualified(ns, n)
+		}
 		f = findFunction(n, a, cn)
 		if f != nil {
 			f.setArgumentList(a)
 		} else {
 			f = newFunction(t, n, a, cn, this, l)
+			f.setDeclaration("", specifiers)
+		}
+		if f.parent() != nil && !f.parent().templateParameters().isEmpty() &&
+			len(templates) > 0 {
+			// the first template header belongs to the class
+			templates = templates[1:]
+		}
+		if len(templates) > 0 && f.templateParameters().isEmpty() {
+			f.setTemplateParameters(templates[0])
 		}
 	} else {
 		docError(this, l, "Unable to parse function name")
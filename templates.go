@@ -0,0 +1,114 @@
+package main
+
+import (
+	"html/template"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+/*! These are the built-in templates WebPage uses to lay out its
+  pages. Each may be replaced by a file in the templates directory
+  named after it, e.g. page.html replaces "page".
+
+  "page" lays out an entire HTML file. Its data has Title, Kind
+  ("class", "namespace", "file", "chapter" or "index"), Name,
+  Content, Owner, OwnerHome, Stylesheet and Index (the name of the
+  index page).
+
+  "class" lays out the Content of a class page. Its data has Name,
+  Headline, Description, Typedefs, Enums, Functions and Variables,
+  the last four being the concatenation of each typedef rendered by
+  "typedef", each enum rendered by "enum", each function rendered by
+  "function" and each member variable rendered by "variable".
+
+  "function" lays out the documentation of one member function. Its
+  data has Name, Anchor (empty if an earlier function on the same
+  page has the same anchor), Headline and Body. "typedef", "enum"
+  and "variable" do the same for a typedef, an enum and a member
+  variable.
+
+  "namespace" lays out the Content of a namespace page. Its data has
+  Name, Headline, Body, Typedefs and Functions, the last two being
+  the typedefs and free functions in the namespace, rendered by
+  "typedef" and "function". "file" does the same for the page of a
+  source file's global typedefs and free functions.
+
+  "chapter" lays out the Content of a chapter (Intro) page. Its data
+  has Name and Body.
+*/
+
+const defaultTemplates = `
+{{define "page"}}<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.0//EN">
+<html lang=en><head><title>{{.Title}}</title>
+<link rel=stylesheet href="{{.Stylesheet}}" type="text/css">
+<link rel=generator href="http://archiveopteryx.org/udoc/">
+</head><body>
+{{.Content}}
+<p class="rights">This web page based on source code belonging to {{if .OwnerHome}}<a href="{{.OwnerHome}}">{{.Owner}}</a>{{else}}{{.Owner}}{{end}}. All rights reserved.</body></html>
+{{end}}
+
+{{define "class"}}<h1 class="classh">{{.Headline}}</h1>
+{{.Description}}
+{{.Typedefs}}{{.Enums}}{{.Functions}}{{.Variables}}{{end}}
+
+{{define "function"}}<h2 class="functionh">{{if .Anchor}}<a name="{{.Anchor}}"></a>{{end}}{{.Headline}}</h2>
+{{.Body}}
+{{end}}
+
+{{define "typedef"}}<h2 class="typedefh">{{if .Anchor}}<a name="{{.Anchor}}"></a>{{end}}{{.Headline}}</h2>
+{{.Body}}
+{{end}}
+
+{{define "enum"}}<h2 class="enumh">{{if .Anchor}}<a name="{{.Anchor}}"></a>{{end}}{{.Headline}}</h2>
+{{.Body}}
+{{end}}
+
+{{define "variable"}}<h2 class="variableh">{{if .Anchor}}<a name="{{.Anchor}}"></a>{{end}}{{.Headline}}</h2>
+{{.Body}}
+{{end}}
+
+{{define "namespace"}}<h1 class="classh">{{.Headline}}</h1>
+{{.Body}}{{.Typedefs}}{{.Functions}}{{end}}
+
+{{define "file"}}<h1 class="classh">{{.Headline}}</h1>
+{{.Body}}{{.Typedefs}}{{.Functions}}{{end}}
+
+{{define "chapter"}}{{.Body}}{{end}}
+`
+
+var templates *template.Template
+
+/*! Loads the page templates: first the built-in ones, then any
+  .html files in directory \a dir, each of which defines (or
+  replaces) the template named after the file. \a dir may be empty,
+  in which case only the built-in templates are used.
+
+  Returns an error if any template cannot be parsed.
+*/
+
+func loadTemplates(dir string) error {
+	t, err := template.New("udoc").Parse(defaultTemplates)
+	if err != nil {
+		return err
+	}
+	if dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				return err
+			}
+			name := strings.TrimSuffix(filepath.Base(f), ".html")
+			_, err = t.New(name).Parse(string(b))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	templates = t
+	return nil
+}
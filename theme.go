@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+/*! This is the stylesheet udoc writes as udoc.css unless the theme
+  supplies its own. It covers the classes WebPage emits.
+*/
+
+const defaultStylesheet = `/* Default udoc stylesheet. */
+
+body {
+	max-width: 50em;
+	margin: 2em auto;
+	padding: 0 1em;
+	font-family: Georgia, "Times New Roman", serif;
+	line-height: 1.45;
+	color: #222;
+	background: #fff;
+}
+
+h1.classh {
+	font-family: Helvetica, Arial, sans-serif;
+	font-size: 1.8em;
+	border-bottom: 2px solid #446;
+	padding-bottom: 0.2em;
+}
+
+h2.functionh, h2.typedefh, h2.enumh, h2.variableh {
+	font-family: "Courier New", Courier, monospace;
+	font-size: 1.05em;
+	font-weight: bold;
+	margin-top: 2em;
+	padding: 0.3em 0.5em;
+	background: #eef;
+	border-left: 4px solid #446;
+}
+
+h1, h2, h3 {
+	font-family: Helvetica, Arial, sans-serif;
+	color: #223;
+}
+
+p.text {
+	margin: 0.6em 0;
+}
+
+p.rights {
+	margin-top: 3em;
+	padding-top: 0.5em;
+	border-top: 1px solid #ccc;
+	font-size: 0.8em;
+	color: #666;
+}
+
+.nobr {
+	white-space: nowrap;
+}
+
+pre {
+	padding: 0.6em 1em;
+	background: #f6f6f6;
+	border: 1px solid #ddd;
+	overflow: auto;
+}
+
+a {
+	color: #24a;
+	text-decoration: none;
+}
+
+a:hover {
+	text-decoration: underline;
+}
+`
+
+/*! Writes the theme assets to the output directory \a dir: first the
+  default udoc.css, then every file in the theme directory \a theme
+  (if any), keeping their relative paths. A theme file named
+  udoc.css thus replaces the default stylesheet.
+*/
+
+func writeTheme(dir, theme string) error {
+	err := ioutil.WriteFile(filepath.Join(dir, "udoc.css"), []byte(defaultStylesheet), 0644)
+	if err != nil {
+		return err
+	}
+	if theme == "" {
+		return nil
+	}
+	return filepath.Walk(theme, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(theme, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("can't read theme file %s: %s", path, err)
+		}
+		return ioutil.WriteFile(target, b, 0644)
+	})
+}
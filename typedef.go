@@ -0,0 +1,315 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+)
+
+/*! \class Typedef typedef.h
+  The Typedef class models a C++ typedef or alias declaration, e.g.
+  "typedef unsigned int Handle;" or "using Callback = void (*)(int);".
+
+  A Typedef is declared either in a class or in a namespace (possibly
+  the global namespace). Its name is fully qualified, e.g.
+  "Shape::Handle". It has an underlying type, an origin file and
+  line, and should have a DocBlock, written using "\typedef Name".
+*/
+
+type Typedef struct {
+	c   *Class
+	n   estring
+	t   estring
+	ni  int
+	f   File
+	l   int
+	acc estring
+	tp  estring
+	db  *DocBlock
+}
+
+var typedefs []*Typedef
+
+/*! Constructs a Typedef called \a name (fully qualified) for the
+  type \a t, declared in class \a c at \a originLine of \a
+  originFile. \a c is a null pointer for a typedef at namespace
+  level.
+
+  \a ni is the position in \a t where the name was written, e.g. 7
+  in "void (*)(int)", or -1 if the alias was declared using "using".
+*/
+
+func newTypedef(c *Class, name, t estring, ni int, originFile File, originLine int) *Typedef {
+	if false {
+		log.Printf("New typedef: %s", name)
+	}
+	td := &Typedef{
+		c:  c,
+		n:  name,
+		t:  t,
+		ni: ni,
+		f:  originFile,
+		l:  originLine,
+	}
+	typedefs = append(typedefs, td)
+	return td
+}
+
+/*! Returns the class this typedef is declared in, or a null pointer
+  if it is declared at namespace level. */
+
+func (this Typedef) parent() *Class {
+	return this.c
+}
+
+/*! Returns the typedef's unqualified name. */
+
+func (this Typedef) name() estring {
+	return unqualified(this.n)
+}
+
+/*! Returns the typedef's fully qualified name, e.g. "Shape::Handle". */
+
+func (this Typedef) fullName() estring {
+	return this.n
+}
+
+/*! Returns the name of the class or namespace this typedef is
+  declared in. */
+
+func (this Typedef) scope() estring {
+	return scopeOf(this.n)
+}
+
+/*! Returns the Namespace this typedef is declared in, or a null
+  pointer if it is in the global namespace or in a class. */
+
+func (this Typedef) namespace() *Namespace {
+	if this.c != nil {
+		return nil
+	}
+	return findNamespace(this.scope())
+}
+
+/*! Returns the underlying type, e.g. "unsigned int" or "void
+  (*)(int)". */
+
+func (this Typedef) typeStr() estring {
+	return this.t
+}
+
+/*! Returns true if this alias was declared using "using", and false
+  if it was declared using "typedef". */
+
+func (this Typedef) isAlias() bool {
+	return this.ni < 0
+}
+
+/*! Returns the underlying type split where a typedef declaration
+  writes the name, e.g. "void (*" and ")(int)", or "unsigned int "
+  and "". For an alias declared using "using", the first part is
+  empty. */
+
+func (this Typedef) declarator() (estring, estring) {
+	if this.isAlias() {
+		return "", this.t
+	}
+	pre := this.t.mid(0, this.ni)
+	post := this.t.mid(this.ni, this.t.length()-this.ni)
+	if !pre.endsWith("*") && !pre.endsWith("&") && !pre.endsWith("(") {
+		pre += " "
+	}
+	return pre, post
+}
+
+func (this Typedef) file() File {
+	return this.f
+}
+
+func (this Typedef) line() int {
+	return this.l
+}
+
+func (this Typedef) docBlock() *DocBlock {
+	return this.db
+}
+
+func (this *Typedef) setDocBlock(d *DocBlock) {
+	this.db = d
+}
+
+/*! Records the \a access level of a typedef declared in a class. */
+
+func (this *Typedef) setAccess(access estring) {
+	this.acc = access
+}
+
+/*! Returns this typedef's access level, "public", "protected" or
+  "private". Typedefs at namespace level are public. */
+
+func (this Typedef) access() estring {
+	if this.acc.isEmpty() {
+		return "public"
+	}
+	return this.acc
+}
+
+/*! Records that this is an alias template with the parameter list
+  \a tp, e.g. "<typename T>". */
+
+func (this *Typedef) setTemplateParameters(tp estring) {
+	this.tp = tp
+}
+
+/*! Returns the template parameter list, or an empty string if this
+  is not an alias template. */
+
+func (this Typedef) templateParameters() estring {
+	return this.tp
+}
+
+/*! Returns true if this typedef's access level is one udoc is
+  configured to document, and false if it should be left out
+  entirely. */
+
+func (this Typedef) isDocumentable() bool {
+	dir := "."
+	if this.f != nil {
+		dir = filepath.Dir(string(this.f.Name()))
+	}
+	return optionsFor(dir).documents(this.access())
+}
+
+/*! Returns true if this typedef has a DocBlock which isn't
+  \internal. */
+
+func (this Typedef) isDocumented() bool {
+	return this.db != nil && !this.db.isInternal()
+}
+
+/*! Returns the anchor (sans '#') corresponding to this typedef,
+  which output backends use to link to it within its page. */
+
+func (this Typedef) anchor() estring {
+	return "type-" + this.name()
+}
+
+/*! Returns the base name of the page documenting this typedef: that
+  of its class, its namespace or, for a typedef in the global
+  namespace, its file. */
+
+func (this Typedef) pageName() estring {
+	if this.c != nil {
+		return this.c.pageName()
+	} else if this.namespace() != nil {
+		return this.namespace().pageName()
+	}
+	return filePageName(this.f.Name())
+}
+
+/*! Returns a pointer to the Typedef whose fully qualified name is \a
+  name, or a null pointer if there is no such typedef. */
+
+func findTypedef(name estring) *Typedef {
+	for _, td := range typedefs {
+		if td.n == name {
+			return td
+		}
+	}
+	return nil
+}
+
+/*! Returns a pointer to the Typedef called \a name as seen from \a
+  scope, which is the fully qualified name of a namespace or class,
+  or a null pointer if there is no such typedef. As for
+  lookupClass(), the innermost match wins.
+*/
+
+func lookupTypedef(name, scope estring) *Typedef {
+	if name.startsWith("::") {
+		return findTypedef(name.mid(2, name.length()-2))
+	}
+	s := scope
+	for {
+		td := findTypedef(qualified(s, name))
+		if td != nil {
+			return td
+		}
+		if s.isEmpty() {
+			return nil
+		}
+		s = scopeOf(s)
+	}
+}
+
+/*! Returns the position and length of the name declared by the
+  declarator \a decl, e.g. 7 and 8 for "void (*Callback)(int)", or
+  -1 and 0 if \a decl declares no name.
+*/
+
+func declaratorName(decl estring) (int, int) {
+	e := decl.length()
+	if i := decl.find("("); i >= 0 {
+		// "(*name)", "(&name)" or "(Class::*name)" is a pointer
+		j := i + 1
+		for decl.at(j) == ' ' || decl.at(j) == ':' ||
+			isIdentifierChar(decl.at(j)) {
+			j++
+		}
+		if decl.at(j) == '*' || decl.at(j) == '&' {
+			j++
+			for decl.at(j) == ' ' {
+				j++
+			}
+			s := j
+			for isIdentifierChar(decl.at(j)) {
+				j++
+			}
+			if j == s {
+				return -1, 0
+			}
+			return s, j - s
+		}
+		// otherwise it's a function type, "name(...)"
+		e = i
+	} else if i := decl.find("["); i >= 0 {
+		e = i
+	}
+	for e > 0 && decl[e-1] == ' ' {
+		e--
+	}
+	s := e
+	for s > 0 && isIdentifierChar(decl[s-1]) {
+		s--
+	}
+	if s == e || (decl[s] >= '0' && decl[s] <= '9') {
+		return -1, 0
+	}
+	return s, e - s
+}
+
+/*! Returns true if \a decl declares several names, e.g. "int a, *b",
+  and false if it declares only one. */
+
+func hasSeveralDeclarators(decl estring) bool {
+	level := 0
+	for i := 0; i < decl.length(); i++ {
+		switch decl[i] {
+		case '(', '<', '[':
+			level++
+		case ')', '>', ']':
+			level--
+		case ',':
+			if level == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*! Returns true if \a c may be part of a C++ identifier. */
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
@@ -1,25 +1,52 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"log"
 	"os"
-	"path/filepath"
-	"strings"
 )
 
 func main() {
-	newWebpage(".")
+	o, err := parseOptions(os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	} else if err == errBadFlag {
+		os.Exit(2)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "udoc:", err)
+		os.Exit(2)
+	}
+	options = o
 
-	filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
+	err = os.MkdirAll(options.outputDir, 0755)
+	if err != nil {
+		log.Fatalf("Can't create output directory %s: %s", options.outputDir, err)
+	}
+	if options.wantsFormat("html") {
+		err = loadTemplates(options.templates)
 		if err != nil {
-			return err
+			log.Fatalf("Can't read templates: %s", err)
 		}
-		if strings.HasSuffix(path, ".cpp") {
-			NewSourceFile(path)
+		err = writeTheme(options.outputDir, options.theme)
+		if err != nil {
+			log.Fatalf("Can't write theme: %s", err)
 		}
-		return nil
-	})
+	}
+	for _, f := range options.formats {
+		output.addBackend(outputFormats[f](estring(options.outputDir)))
+	}
+	output.setOwner(estring(options.owner))
+	output.setOwnerHome(estring(options.ownerHome))
+
+	err = options.scanInputs()
+	if err != nil {
+		log.Fatalf("Can't scan input: %s", err)
+	}
 	buildHierarchy()
 	outputIntro()
 	outputClasses()
-	webpage.endPage()
+	outputNamespaces()
+	outputFiles()
+	output.finish()
 }
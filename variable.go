@@ -0,0 +1,159 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+)
+
+/*! \class Variable variable.h
+  The Variable class models a C++ member variable, including static
+  data members and constants.
+
+  A Variable belongs to a Class, has a type, an optional initializer,
+  an origin file and line, and should have a DocBlock, written using
+  "\var Class::name".
+*/
+
+type Variable struct {
+	c    *Class
+	t    estring
+	n    estring
+	init estring
+	f    File
+	l    int
+	acc  estring
+	spec estringlist
+	db   *DocBlock
+}
+
+/*! Constructs a Variable named \a name of type \a type in class \a
+  c, declared at \a originLine of \a originFile, and adds it to \a
+  c. \a init is the initializer, or empty if there is none.
+*/
+
+func newVariable(c *Class, t, name, init estring, originFile File, originLine int) *Variable {
+	if false {
+		log.Printf("New variable: %s::%s", c.name(), name)
+	}
+	v := &Variable{
+		c:    c,
+		t:    t,
+		n:    name,
+		init: init,
+		f:    originFile,
+		l:    originLine,
+	}
+	c.insertVariable(v)
+	return v
+}
+
+/*! Returns the class this variable is a member of. */
+
+func (this Variable) parent() *Class {
+	return this.c
+}
+
+/*! Returns the variable's unqualified name. */
+
+func (this Variable) name() estring {
+	return this.n
+}
+
+/*! Returns the variable's fully qualified name, e.g. "Shape::count". */
+
+func (this Variable) fullName() estring {
+	return this.c.name() + "::" + this.n
+}
+
+func (this Variable) typeStr() estring {
+	return this.t
+}
+
+/*! Returns the initializer given in the class declaration, or an
+  empty string if there is none. */
+
+func (this Variable) initializer() estring {
+	return this.init
+}
+
+func (this Variable) file() File {
+	return this.f
+}
+
+func (this Variable) line() int {
+	return this.l
+}
+
+func (this Variable) docBlock() *DocBlock {
+	return this.db
+}
+
+func (this *Variable) setDocBlock(d *DocBlock) {
+	this.db = d
+}
+
+/*! Records what the class declaration says about this variable: its
+  \a access level and its \a specifiers, e.g. "static" or "mutable".
+*/
+
+func (this *Variable) setDeclaration(access estring, specifiers estringlist) {
+	this.acc = access
+	this.spec = specifiers
+}
+
+/*! Returns this variable's access level, "public", "protected" or
+  "private". */
+
+func (this Variable) access() estring {
+	if this.acc.isEmpty() {
+		return "public"
+	}
+	return this.acc
+}
+
+/*! Returns the specifiers given in the class declaration, in the
+  order they were written. */
+
+func (this Variable) specifiers() estringlist {
+	return this.spec
+}
+
+func (this Variable) isStatic() bool {
+	return this.spec.contains("static")
+}
+
+/*! Returns true if this variable's access level is one udoc is
+  configured to document, and false if it should be left out
+  entirely. */
+
+func (this Variable) isDocumentable() bool {
+	dir := "."
+	if this.f != nil {
+		dir = filepath.Dir(string(this.f.Name()))
+	}
+	return optionsFor(dir).documents(this.access())
+}
+
+/*! Returns the anchor (sans '#') corresponding to this variable,
+  which output backends use to link to it within its class's page.
+*/
+
+func (this Variable) anchor() estring {
+	return "var-" + this.n
+}
+
+/*! Returns a pointer to the Variable whose qualified name is \a name
+  as seen from namespace \a ns, or a null pointer if there is no such
+  variable.
+*/
+
+func findVariable(name, ns estring) *Variable {
+	if scopeOf(name).isEmpty() {
+		return nil
+	}
+	c := lookupClass(scopeOf(name), ns)
+	if c == nil {
+		return nil
+	}
+	return c.variableNamed(unqualified(name))
+}
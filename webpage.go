@@ -1,19 +1,24 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
-	"os"
+	"html/template"
+	"io/ioutil"
+	"sort"
 )
 
 /*! \class WebPage webpage.h
   The WebPage class provides documentation output to a web page.
 
-  It implements the same functions as Output, but they're not static,
-  and is called when Output's static functions are called.
-*/
+  It implements OutputBackend, and is called when Output's static
+  functions are called.
 
-/*! Constructs a web page generator that'll write to files in
-  directory \a dir. */
+  The HTML it generates for the documentation text is collected per
+  page and laid out using the templates (see loadTemplates()): each
+  member function by "function", the class or chapter by "class" or
+  "chapter", and the whole file by "page".
+*/
 
 type estringlist []estring
 
@@ -32,18 +37,40 @@ func (this *estringlist) clear() {
 }
 
 type webpageT struct {
-	fd        *os.File
-	directory estring
-	pstart    bool
-	para      estring
-	names     estringlist
-	fn        estring
+	open       bool
+	directory  estring
+	pstart     bool
+	para       estring
+	names      estringlist
+	fn         estring
+	title      estring
+	kind       string
+	name       estring
+	inHeadline bool
+	section    string
+	headline   estring
+	body       estring
+	typedefs   estring
+	enums      estring
+	functions  estring
+	variables  estring
+	sHeadline  estring
+	sBody      estring
+	sAnchor    estring
+	sName      estring
 }
 
-var webpage *webpageT
+/*! Constructs a web page generator that'll write to files in
+  directory \a dir. */
 
-func newWebpage(dir estring) {
-	webpage = &webpageT{
+func newWebpage(dir estring) *webpageT {
+	if templates == nil {
+		err := loadTemplates("")
+		if err != nil {
+			panic(fmt.Sprintf("Can't parse built-in templates: %s", err))
+		}
+	}
+	return &webpageT{
 		directory: dir,
 		pstart:    false,
 	}
@@ -54,35 +81,100 @@ func newWebpage(dir estring) {
 func (this *webpageT) startHeadlineIntro(i *Intro) {
 	this.endPage()
 	this.startPage(i.name().lower(), i.name())
+	this.kind = "chapter"
+	this.name = i.name()
 }
 
 /*! As Output::startHeadline(). \a c is used to derive a file name. */
 
 func (this *webpageT) startHeadlineClass(c *Class) {
 	this.endPage()
-	this.startPage(c.name().lower(), c.name()+" documentation")
-	this.output("<h1 class=\"classh\">")
-	this.para = "</h1>\n"
+	this.startPage(c.pageName(), c.name()+" documentation")
+	this.kind = "class"
+	this.name = c.name()
+	this.inHeadline = true
+	this.para = "\n"
+	this.pstart = true
+}
+
+/*! As Output::startHeadline(). \a n is used to derive a file name. */
+
+func (this *webpageT) startHeadlineNamespace(n *Namespace) {
+	this.endPage()
+	this.startPage(n.pageName(), "Namespace "+n.name())
+	this.kind = "namespace"
+	this.name = n.name()
+	this.inHeadline = true
+	this.para = "\n"
+	this.pstart = true
+}
+
+/*! As Output::startHeadline(). \a p is used to derive a file name. */
+
+func (this *webpageT) startHeadlineFile(p *FilePage) {
+	this.endPage()
+	this.startPage(p.pageName(), "File "+p.name())
+	this.kind = "file"
+	this.name = p.name()
+	this.inHeadline = true
+	this.para = "\n"
 	this.pstart = true
 }
 
 /*! As Output::startHeadline(). \a f is used to create an anchor. */
 
 func (this *webpageT) startHeadlineFunction(f *Function) {
-	a := this.anchor(f)
-	o := estring("<h2 class=\"functionh\">")
+	this.startSection("function", f.name(), f.anchor())
+}
+
+/*! As Output::startHeadlineEnum(). \a e is used to create an
+  anchor. */
+
+func (this *webpageT) startHeadlineEnum(e *Enum) {
+	this.startSection("enum", e.fullName(), e.anchor())
+}
+
+/*! As Output::startHeadlineVariable(). \a v is used to create an
+  anchor. */
+
+func (this *webpageT) startHeadlineVariable(v *Variable) {
+	this.startSection("variable", v.fullName(), v.anchor())
+}
+
+/*! As Output::startHeadlineTypedef(). \a t is used to create an
+  anchor. */
+
+func (this *webpageT) startHeadlineTypedef(t *Typedef) {
+	this.startSection("typedef", t.fullName(), t.anchor())
+}
+
+/*! Starts a section of kind \a kind (also the name of the template
+  which lays it out) documenting \a name, with anchor \a a. The
+  section starts with its headline. */
+
+func (this *webpageT) startSection(kind string, name, a estring) {
+	this.endSection()
+	this.sAnchor = ""
 	if !this.names.contains(a) {
-		o += "<a name=\"" + this.anchor(f) + "\"></a>"
+		this.sAnchor = a
 		this.names = append(this.names, a)
 	}
-	this.output(o)
-	this.para = "</h2>\n"
+	this.sName = name
+	this.section = kind
+	this.inHeadline = true
+	this.para = "\n"
 	this.pstart = true
 }
 
-/*! As Output::endParagraph(). */
+/*! As Output::endParagraph(). Ending the paragraph that makes up a
+  headline ends the headline. */
 
 func (this *webpageT) endParagraph() {
+	if this.inHeadline {
+		this.inHeadline = false
+		this.para = ""
+		return
+	}
 	if this.para.isEmpty() {
 		return
 	}
@@ -90,6 +182,47 @@ func (this *webpageT) endParagraph() {
 	this.para = ""
 }
 
+/*! Lays out the current section, if any, using the template named
+  after its kind, and adds the result to the page's list of
+  typedefs, functions, enums or variables. */
+
+func (this *webpageT) endSection() {
+	if this.section == "" {
+		return
+	}
+	this.endParagraph()
+	r := this.execute(this.section, map[string]interface{}{
+		"Name":     string(this.sName),
+		"Anchor":   string(this.sAnchor),
+		"Headline": template.HTML(this.sHeadline),
+		"Body":     template.HTML(this.sBody),
+	})
+	if this.section == "typedef" {
+		this.typedefs += r
+	} else if this.section == "enum" {
+		this.enums += r
+	} else if this.section == "variable" {
+		this.variables += r
+	} else {
+		this.functions += r
+	}
+	this.section = ""
+	this.sHeadline = ""
+	this.sBody = ""
+}
+
+/*! Executes the template called \a name with \a data and returns
+  the result. */
+
+func (this *webpageT) execute(name string, data interface{}) estring {
+	var b bytes.Buffer
+	err := templates.ExecuteTemplate(&b, name, data)
+	if err != nil {
+		panic(fmt.Sprintf("Can't execute template %s: %s", name, err))
+	}
+	return estring(b.String())
+}
+
 func escape(text estring) estring {
 	s := make([]rune, 0, len(text))
 	for _, c := range text {
@@ -157,47 +290,19 @@ func (this *webpageT) addArgument(text estring) {
 */
 
 func (this *webpageT) addFunction(text estring, f *Function) {
-	name := f.name()
-	ll := text.length()
-	ls := text.find(name)
-	// if we don't find the complete function name, try just the member part
-	if ls < 0 {
-		i := name.length()
-		for i > 0 && name.at(i) != ':' {
-			i--
-		}
-		if i > 0 {
-			name = name.mid(i+1, len(name)-i+1)
-			ls = text.find(name)
-		}
-	}
-	if ls >= 0 {
-		ll = name.length()
-	} else {
-		ls = 0
-	}
-	if ll < text.length() && text.mid(ls+ll, 2) == "()" {
-		ll = ll + 2
-	}
+	ls, ll := functionLinkSpan(text, f)
 	this.addText("")
-	space := false
-	i := 0
-	for i < text.length() && !space {
-		if text[i] == ' ' {
-			space = true
-		}
-		i++
-	}
+	space := text.contains(" ")
 	if space {
 		this.output("<span class=nobr>")
 	}
 	this.addText(text.mid(0, ls))
 	this.output("<a href=\"")
-	target := f.parent().name().lower()
+	target := f.pageName()
 	if this.fn != target {
 		this.output(target)
 	}
-	this.output("#" + this.anchor(f) + "\">")
+	this.output("#" + f.anchor() + "\">")
 	this.addText(text.mid(ls, ll))
 	this.output("</a>")
 	this.addText(text.mid(ls+ll, len(text)-ls+ll))
@@ -212,28 +317,15 @@ func (this *webpageT) addFunction(text estring, f *Function) {
 */
 
 func (this *webpageT) addClass(text estring, c *Class) {
-	ll := text.length()
-	ls := text.find(c.name())
-	if ls >= 0 {
-		ll = c.name().length()
-	} else {
-		ls = 0
-	}
+	ls, ll := classLinkSpan(text, c)
 	this.addText("")
-	space := false
-	i := 0
-	for i < text.length() && !space {
-		if text[i] == ' ' {
-			space = true
-		}
-		i++
-	}
+	space := text.contains(" ")
 	if space {
 		this.output("<span class=nobr>")
 	}
 	this.addText(text.mid(0, ls))
 	link := true
-	target := c.name().lower()
+	target := c.pageName()
 	if target == this.fn {
 		link = false
 	}
@@ -250,6 +342,79 @@ func (this *webpageT) addClass(text estring, c *Class) {
 	}
 }
 
+/*! As Output::addNamespace(). \a text is linked to the page of \a
+  n, unless that is the current page. */
+
+func (this *webpageT) addNamespace(text estring, n *Namespace) {
+	this.addText("")
+	target := n.pageName()
+	if target == this.fn {
+		this.addText(text)
+		return
+	}
+	this.output("<a href=\"" + target + "\">")
+	this.addText(text)
+	this.output("</a>")
+}
+
+/*! As Output::addEnum(). The part of \a text which corresponds to
+  the name of \a e or one of its values is made into a link. */
+
+func (this *webpageT) addEnum(text estring, e *Enum) {
+	ls, ll := enumLinkSpan(text, e)
+	this.addText(text.mid(0, ls))
+	this.output("<a href=\"")
+	target := e.parent().pageName()
+	if this.fn != target {
+		this.output(target)
+	}
+	this.output("#" + e.anchor() + "\">")
+	this.addText(text.mid(ls, ll))
+	this.output("</a>")
+	this.addText(text.mid(ls+ll, len(text)-ls+ll))
+}
+
+/*! As Output::addVariable(). \a text is made into a link to \a v.
+ */
+
+func (this *webpageT) addVariable(text estring, v *Variable) {
+	this.addText("")
+	this.output("<a href=\"")
+	target := v.parent().pageName()
+	if this.fn != target {
+		this.output(target)
+	}
+	this.output("#" + v.anchor() + "\">")
+	this.addText(text)
+	this.output("</a>")
+}
+
+/*! As Output::addTypedef(). The part of \a text which corresponds
+  to the name of \a t is made into a link. */
+
+func (this *webpageT) addTypedef(text estring, t *Typedef) {
+	ls, ll := typedefLinkSpan(text, t)
+	this.addText(text.mid(0, ls))
+	this.output("<a href=\"")
+	target := t.pageName()
+	if this.fn != target {
+		this.output(target)
+	}
+	this.output("#" + t.anchor() + "\">")
+	this.addText(text.mid(ls, ll))
+	this.output("</a>")
+	this.addText(text.mid(ls+ll, len(text)-ls+ll))
+}
+
+/*! As Output::addEnumValue(). \a text is output in bold. */
+
+func (this *webpageT) addEnumValue(text estring, e *Enum) {
+	this.addText("")
+	this.output("<b>")
+	this.addText(text)
+	this.output("</b>")
+}
+
 func (this *webpageT) addCodeBlock(text estring) {
 	this.output("<pre>")
 	this.output(escape(text))
@@ -285,56 +450,211 @@ func (this *webpageT) addSection(prio int, text estring) {
 	}
 }
 
-/*! Write \a s to the output file. */
+/*! Adds \a s to the current headline, function or page body. */
 
 func (this *webpageT) output(s estring) {
-	if this.fd == nil || s.isEmpty() {
+	if !this.open || s.isEmpty() {
 		return
 	}
 
-	this.fd.Write([]byte(s))
+	if this.inHeadline && this.section != "" {
+		this.sHeadline += s
+	} else if this.inHeadline {
+		this.headline += s
+	} else if this.section != "" {
+		this.sBody += s
+	} else {
+		this.body += s
+	}
 }
 
-/*! This private helper returns the anchor (sans '#') corresponding to
-  \a f.
-*/
+/*! Lays out the current page using the templates and writes it to
+  its file. */
 
-func (this *webpageT) anchor(f *Function) estring {
-	fn := f.name()
-	i := fn.length()
-	for i > 0 && fn.at(i) != ':' {
-		i--
+func (this *webpageT) endPage() {
+	if !this.open {
+		return
 	}
-	if i > 0 {
-		fn = fn.mid(i+1, len(fn)-i+1)
+
+	this.endParagraph()
+	this.endSection()
+
+	content := this.body
+	if this.kind == "class" {
+		content = this.execute("class", map[string]interface{}{
+			"Name":        string(this.name),
+			"Headline":    template.HTML(this.headline),
+			"Description": template.HTML(this.body),
+			"Typedefs":    template.HTML(this.typedefs),
+			"Enums":       template.HTML(this.enums),
+			"Functions":   template.HTML(this.functions),
+			"Variables":   template.HTML(this.variables),
+		})
+	} else if this.kind == "namespace" || this.kind == "file" {
+		content = this.execute(this.kind, map[string]interface{}{
+			"Name":      string(this.name),
+			"Headline":  template.HTML(this.headline),
+			"Body":      template.HTML(this.body),
+			"Typedefs":  template.HTML(this.typedefs),
+			"Functions": template.HTML(this.functions),
+		})
+	} else if this.kind == "chapter" {
+		content = this.execute("chapter", map[string]interface{}{
+			"Name": string(this.name),
+			"Body": template.HTML(this.body),
+		})
 	}
-	if fn.startsWith("~") {
-		fn = "destructor"
+	page := this.execute("page", map[string]interface{}{
+		"Title":      string(this.title),
+		"Kind":       this.kind,
+		"Name":       string(this.name),
+		"Content":    template.HTML(content),
+		"Owner":      string(output.owner()),
+		"OwnerHome":  string(output.ownerHome()),
+		"Stylesheet": "udoc.css",
+		"Index":      "index",
+	})
+
+	filename := this.directory + "/" + this.fn
+	err := ioutil.WriteFile(string(filename), []byte(page), 0644)
+	if err != nil {
+		panic(fmt.Sprintf("Can't write %s: %s", filename, err))
 	}
-	return fn
+	this.open = false
 }
 
-/*! Emits any boilerplate to be emitted at the end of each page. */
+/*! Writes the index page, which lists all chapters, namespaces,
+  files with free functions and documented classes, the latter with
+  the first sentence of their documentation, and an A-Z index of all
+  documented functions.
+*/
 
-func (this *webpageT) endPage() {
-	if this.fd == nil {
-		return
+func (this *webpageT) writeIndex() {
+	this.endPage()
+	this.startPage("index", "Index")
+	this.kind = "index"
+	this.output("<h1 class=\"classh\">Index</h1>\n")
+
+	if len(intros) > 0 {
+		this.output("<h2>Chapters</h2>\n<ul>\n")
+		for _, i := range intros {
+			if i.docBlock == nil || i.docBlock.isInternal() {
+				continue
+			}
+			this.output("<li><a href=\"" + i.name().lower() + "\">" +
+				escape(i.name()) + "</a>\n")
+		}
+		this.output("</ul>\n")
 	}
 
-	this.endParagraph()
+	namespaces := documentedNamespaces()
+	if len(namespaces) > 0 {
+		this.output("<h2>Namespaces</h2>\n<ul>\n")
+		for _, n := range namespaces {
+			this.output("<li><a href=\"" + n.pageName() + "\">" +
+				escape(n.name()) + "</a>\n")
+		}
+		this.output("</ul>\n")
+	}
 
-	this.para = "\n"
-	this.output("<p class=\"rights\">This web page based on source code belonging to ")
-	if !output.ownerHome().isEmpty() {
-		this.output("<a href=\"" + output.ownerHome() + "\">")
-		this.addText(output.owner())
-		this.output("</a>. All rights reserved.")
-	} else {
-		this.addText(output.owner())
-		this.output(". All rights reserved.")
+	files := documentedFiles()
+	if len(files) > 0 {
+		this.output("<h2>Files</h2>\n<ul>\n")
+		for _, p := range files {
+			this.output("<li><a href=\"" + p.pageName() + "\">" +
+				escape(p.name()) + "</a>\n")
+		}
+		this.output("</ul>\n")
+	}
+
+	var members []*Function
+	for _, f := range functions {
+		if f.parent() == nil && f.docBlock() != nil &&
+			!f.docBlock().isInternal() {
+			members = append(members, f)
+		}
+	}
+	classes := documentedClasses()
+	if len(classes) > 0 {
+		this.output("<h2>Classes</h2>\n<ul>\n")
+		for _, c := range classes {
+			this.output("<li><a href=\"" + c.pageName() + "\">" +
+				escape(c.name()) + "</a>")
+			s := c.db.firstSentence()
+			if !s.isEmpty() {
+				this.output(": " + escape(s))
+			}
+			this.output("\n")
+			for _, f := range c.members() {
+				if f.docBlock() != nil && !f.docBlock().isInternal() &&
+					f.isDocumentable() {
+					members = append(members, f)
+				}
+			}
+		}
+		this.output("</ul>\n")
+	}
+
+	if len(members) > 0 {
+		sort.SliceStable(members, func(i, j int) bool {
+			a := indexKey(members[i])
+			b := indexKey(members[j])
+			if a != b {
+				return a < b
+			}
+			return members[i].name() < members[j].name()
+		})
+		this.output("<h2>Functions</h2>\n<p class=\"text\">")
+		var letters estringlist
+		for _, f := range members {
+			l := indexLetter(f)
+			if !letters.contains(l) {
+				this.output("<a href=\"#index-" + l.lower() + "\">" + l + "</a>\n")
+				letters = append(letters, l)
+			}
+		}
+		this.output("</p>\n")
+		var current estring
+		for _, f := range members {
+			l := indexLetter(f)
+			if l != current {
+				if !current.isEmpty() {
+					this.output("</ul>\n")
+				}
+				this.output("<h3><a name=\"index-" + l.lower() + "\"></a>" +
+					l + "</h3>\n<ul>\n")
+				current = l
+			}
+			this.output("<li><a href=\"" + f.pageName() + "#" +
+				f.anchor() + "\">" + escape(f.name()+"()") + "</a>\n")
+		}
+		this.output("</ul>\n")
 	}
-	this.output("</body></html>\n")
-	this.fd.Close()
+}
+
+/*! Returns the key by which \a f is sorted in the index: its
+  lower-cased member name, without the '~' of a destructor.
+*/
+
+func indexKey(f *Function) estring {
+	n := f.name()
+	i := n.length()
+	for i > 0 && n.at(i) != ':' {
+		i--
+	}
+	if i > 0 {
+		n = n.mid(i+1, n.length()-i-1)
+	}
+	if n.startsWith("~") {
+		n = n.mid(1, n.length()-1)
+	}
+	return n.lower()
+}
+
+/*! Returns the letter under which \a f is listed in the index. */
+
+func indexLetter(f *Function) estring {
+	return indexKey(f).mid(0, 1).upper()
 }
 
 /*! Starts a new web page with base name \a name and title tag \a
@@ -343,17 +663,17 @@ func (this *webpageT) endPage() {
 
 func (this *webpageT) startPage(name, title estring) {
 	this.names.clear()
-	filename := this.directory + "/" + name
-	var err error
-	this.fd, err = os.Create(string(filename))
-	if err != nil {
-		panic(fmt.Sprintf("Can't write %s: %s", filename, err))
-	}
-	this.output("<!DOCTYPE HTML PUBLIC \"-//W3C//DTD HTML 4.0//EN\">\n<html lang=en><head>")
-	this.output("<title>")
-	this.para = "\n"
+	this.open = true
+	this.fn = name
+	this.title = title
+	this.kind = ""
+	this.name = ""
+	this.headline = ""
+	this.body = ""
+	this.typedefs = ""
+	this.enums = ""
+	this.functions = ""
+	this.variables = ""
+	this.para = ""
 	this.pstart = true
-	this.addText(title)
-	this.output("</title>\n")
-	this.output("<link rel=stylesheet href=\"udoc.css\" type=\"text/css\">\n<link rel=generator href=\"http://archiveopteryx.org/udoc/\">\n</head><body>\n")
 }